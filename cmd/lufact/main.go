@@ -0,0 +1,445 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+// Command lufact factorizes sparse matrices read from Matrix Market,
+// Harwell-Boeing, SciPy .npz or MATLAB MAT-files and solves linear
+// systems with the factors.
+// Real matrices are factorized with package gpd and complex matrices
+// with package gpz.
+//
+// Usage:
+//
+//	lufact factor [flags] matrix
+//	lufact solve [flags] matrix
+//	lufact bench [flags] matrix
+//
+// The factor command reports the statistics of the factorization and,
+// with -mat, writes A and the factors L, U, P and Q of PAQ = LU to a
+// MAT-file for comparison with [L,U,P,Q] = lu(A) in MATLAB. The
+// solve command solves Ax = b, with b read from the file given by -rhs,
+// taken from the right-hand sides of a Harwell-Boeing matrix file or
+// computed as A times a vector of ones, and writes x in Matrix Market
+// array format. The bench command times repeated
+// factorizations. Run "lufact <command> -h" for the flags.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rwl/lufact/gpd"
+	"github.com/rwl/lufact/gpz"
+	"github.com/rwl/lufact/sparse"
+	"github.com/rwl/lufact/sparse/hb"
+	"github.com/rwl/lufact/sparse/mat"
+	"github.com/rwl/lufact/sparse/mm"
+	"github.com/rwl/lufact/sparse/npz"
+)
+
+const usage = `usage: lufact <command> [flags] matrix
+
+Commands:
+  factor  factorize the matrix and report the statistics
+  solve   solve a linear system with the matrix
+  bench   time repeated factorizations
+`
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	if err == errUsage || err == flag.ErrHelp {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "lufact:", err)
+		os.Exit(1)
+	}
+}
+
+// factorFlags holds the flags that select the factorization options.
+type factorFlags struct {
+	format     string
+	verbose    bool
+	pivot      float64
+	noPivot    bool
+	drop       float64
+	colFill    float64
+	colPerm    string
+	rankTol    float64
+	shift      float64
+	workers    int
+	supernodal bool
+	dense      float64
+	maxNNZ     int
+	wide       bool
+}
+
+func (f *factorFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.format, "format", "auto", "matrix file `format`: mm, hb, npz, mat or auto to use the extension")
+	fs.BoolVar(&f.verbose, "v", false, "log the progress of the factorization")
+	fs.Float64Var(&f.pivot, "pivot", 1, "partial pivoting `threshold`")
+	fs.BoolVar(&f.noPivot, "nopivot", false, "disable pivoting")
+	fs.Float64Var(&f.drop, "drop", 0, "drop `threshold` for an incomplete factorization")
+	fs.Float64Var(&f.colFill, "colfill", -1, "column fill `ratio` limit for an incomplete factorization")
+	fs.StringVar(&f.colPerm, "colperm", "", "`file` of the zero-based column ordering (default natural)")
+	fs.Float64Var(&f.rankTol, "rank", 0, "rank-revealing factorization with the given `tolerance`")
+	fs.Float64Var(&f.shift, "shift", 0, "initial diagonal `shift` for breakdown recovery")
+	fs.IntVar(&f.workers, "workers", 1, "number of `goroutines` for parallel factorization, 0 for GOMAXPROCS")
+	fs.BoolVar(&f.supernodal, "supernodal", false, "enable supernodal updates")
+	fs.Float64Var(&f.dense, "dense", 0, "switch to dense for the trailing submatrix at the given `density`")
+	fs.IntVar(&f.maxNNZ, "maxnnz", 0, "limit on the `number` of nonzeros in L and U")
+	fs.BoolVar(&f.wide, "wide", false, "store the indexes of the factors as int")
+}
+
+// options returns the factorization options for gpd and gpz.
+func (f *factorFlags) options(n int) (d []gpd.OptFunc, z []gpz.OptFunc, err error) {
+	add := func(od gpd.OptFunc, oz gpz.OptFunc) {
+		d = append(d, od)
+		z = append(z, oz)
+	}
+	if f.noPivot {
+		add(gpd.WithoutPivoting(), gpz.WithoutPivoting())
+	} else {
+		add(gpd.PartialPivoting(f.pivot), gpz.PartialPivoting(f.pivot))
+	}
+	if f.drop > 0 {
+		add(gpd.DropThreshold(f.drop), gpz.DropThreshold(f.drop))
+	}
+	if f.colFill > 0 {
+		add(gpd.ColFillRatio(f.colFill), gpz.ColFillRatio(f.colFill))
+	}
+	if f.colPerm != "" {
+		perm, err := readPerm(f.colPerm, n)
+		if err != nil {
+			return nil, nil, err
+		}
+		add(gpd.ColPerm(perm), gpz.ColPerm(perm))
+	}
+	if f.rankTol > 0 {
+		add(gpd.RankDeficient(f.rankTol), gpz.RankDeficient(f.rankTol))
+	}
+	if f.shift > 0 {
+		add(gpd.DiagonalShift(f.shift, 0), gpz.DiagonalShift(f.shift, 0))
+	}
+	if f.workers != 1 {
+		add(gpd.Parallel(f.workers), gpz.Parallel(f.workers))
+	}
+	if f.supernodal {
+		add(gpd.Supernodal(), gpz.Supernodal())
+	}
+	if f.dense > 0 {
+		add(gpd.DenseThreshold(f.dense), gpz.DenseThreshold(f.dense))
+	}
+	if f.maxNNZ > 0 {
+		add(gpd.MaxLUNonzeros(f.maxNNZ), gpz.MaxLUNonzeros(f.maxNNZ))
+	}
+	if f.wide {
+		add(gpd.WideIndexes(), gpz.WideIndexes())
+	}
+	return d, z, nil
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) < 1 {
+		fmt.Fprint(stderr, usage)
+		return errUsage
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: lufact %s [flags] matrix\n\nFlags:\n", cmd)
+		fs.PrintDefaults()
+	}
+	var f factorFlags
+	f.register(fs)
+	var (
+		matFile *string
+		rhs     *string
+		out     *string
+		trans   *bool
+		count   *int
+	)
+	switch cmd {
+	case "factor":
+		matFile = fs.String("mat", "", "MAT-`file` for A and the factors L, U, P and Q")
+	case "solve":
+		rhs = fs.String("rhs", "", "`file` of the right-hand sides, a matrix or a Harwell-Boeing file with right-hand sides (default those of the matrix file or A times a vector of ones)")
+		out = fs.String("o", "", "output `file` for the solution (default standard output)")
+		trans = fs.Bool("trans", false, "solve with the transpose of the matrix")
+	case "bench":
+		count = fs.Int("count", 10, "`number` of factorizations")
+	default:
+		fmt.Fprint(stderr, usage)
+		return errUsage
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	a, b, err := readMatrix(fs.Arg(0), f.format)
+	if err != nil {
+		return err
+	}
+	if a.Rows != a.Cols {
+		return fmt.Errorf("matrix must be square (%v by %v)", a.Rows, a.Cols)
+	}
+	if a.IsPattern() {
+		return errors.New("pattern matrix has no values")
+	}
+	if f.verbose {
+		gpd.Logger = stderr
+		gpz.Logger = stderr
+	}
+	s, err := newSolver(a, &f)
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "factor":
+		return factor(stdout, fs.Arg(0), a, s, *matFile)
+	case "solve":
+		return solve(stdout, stderr, a, b, s, *rhs, *out, *trans)
+	default:
+		return bench(stdout, s, *count)
+	}
+}
+
+func factor(w io.Writer, path string, a *sparse.CSC, s solver, matFile string) error {
+	start := time.Now()
+	if err := s.factor(); err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	st, err := s.stats()
+	if err != nil {
+		return err
+	}
+	kind := "real"
+	if a.IsComplex() {
+		kind = "complex"
+	}
+	fmt.Fprintf(w, "matrix    %s (%d by %d, %s)\n", path, a.Rows, a.Cols, kind)
+	fmt.Fprintf(w, "nnz(A)    %d\n", a.NNZ())
+	fmt.Fprintf(w, "nnz(L+U)  %d (fill %.3g)\n", st.nnz, float64(st.nnz)/float64(a.NNZ()))
+	fmt.Fprintf(w, "rank      %d\n", st.rank)
+	fmt.Fprintf(w, "time      %v\n", elapsed)
+	fmt.Fprintf(w, "residual  %.3e\n", st.resid)
+	if st.dropped != 0 {
+		fmt.Fprintf(w, "dropped   %.3e\n", st.dropped)
+	}
+	if matFile == "" {
+		return nil
+	}
+
+	l, u, p, q, err := s.factors()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(matFile)
+	if err != nil {
+		return err
+	}
+	err = mat.Write(f, []mat.Var{
+		{Name: "A", A: a},
+		{Name: "L", A: l},
+		{Name: "U", A: u},
+		{Name: "P", A: p},
+		{Name: "Q", A: q},
+	}, true)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func solve(stdout, stderr io.Writer, a, b *sparse.CSC, s solver, rhs, out string, trans bool) error {
+	if rhs != "" {
+		m, mb, err := readMatrix(rhs, "auto")
+		if err != nil {
+			return err
+		}
+		b = m
+		if mb != nil {
+			b = mb
+		}
+	}
+	if b != nil {
+		if b.Rows != a.Rows || b.Cols < 1 || b.IsPattern() {
+			return fmt.Errorf("right-hand side must have %d rows and values", a.Rows)
+		}
+	}
+	if err := s.factor(); err != nil {
+		return err
+	}
+
+	var (
+		resid float64
+		err   error
+	)
+	if out == "" {
+		resid, err = s.solve(b, trans, stdout)
+	} else {
+		f, ferr := os.Create(out)
+		if ferr != nil {
+			return ferr
+		}
+		resid, err = s.solve(b, trans, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "residual  %.3e\n", resid)
+	return nil
+}
+
+func bench(w io.Writer, s solver, count int) error {
+	if count < 1 {
+		return fmt.Errorf("count (%v) must be >= 1", count)
+	}
+	var total, min, max time.Duration
+	for i := 0; i < count; i++ {
+		start := time.Now()
+		if err := s.factor(); err != nil {
+			return err
+		}
+		d := time.Since(start)
+		total += d
+		if i == 0 || d < min {
+			min = d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	fmt.Fprintf(w, "factorizations  %d\n", count)
+	fmt.Fprintf(w, "mean            %v\n", total/time.Duration(count))
+	fmt.Fprintf(w, "min             %v\n", min)
+	fmt.Fprintf(w, "max             %v\n", max)
+
+	start := time.Now()
+	if _, err := s.solve(nil, false, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "solve           %v\n", time.Since(start))
+	return nil
+}
+
+// readMatrix reads the matrix, and the right-hand sides of a
+// Harwell-Boeing file, in the file with the given path. The format is
+// chosen by the file extension if it is auto.
+func readMatrix(path, format string) (a, rhs *sparse.CSC, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	if format == "auto" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".mtx", ".mm":
+			format = "mm"
+		case ".npz":
+			format = "npz"
+		case ".mat":
+			format = "mat"
+		case ".hb", ".rb", ".rua", ".rsa", ".rza", ".rra", ".cua", ".csa", ".cha", ".cza", ".cra", ".pua", ".psa", ".pza", ".pra":
+			format = "hb"
+		default:
+			switch head, _ := r.Peek(2); string(head) {
+			case "%%":
+				format = "mm"
+			case "PK":
+				format = "npz"
+			case "MA":
+				format = "mat"
+			default:
+				format = "hb"
+			}
+		}
+	}
+	switch format {
+	case "mm":
+		a, err = mm.Read(r)
+		return a, nil, err
+	case "hb":
+		hf, err := hb.Read(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		a, err = hf.Matrix()
+		return a, hf.RHS, err
+	case "npz":
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, nil, err
+		}
+		a, err = npz.Read(f, fi.Size())
+		return a, nil, err
+	case "mat":
+		vars, err := mat.Read(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return matVar(vars)
+	}
+	return nil, nil, fmt.Errorf("unknown format %q", format)
+}
+
+// matVar returns the variable named A, or the only variable, of a
+// MAT-file.
+func matVar(vars []mat.Var) (a, rhs *sparse.CSC, err error) {
+	for _, v := range vars {
+		if v.Name == "A" {
+			return v.A, nil, nil
+		}
+	}
+	if len(vars) != 1 {
+		return nil, nil, fmt.Errorf("MAT-file has %d matrices and none named A", len(vars))
+	}
+	return vars[0].A, nil, nil
+}
+
+// readPerm reads a permutation of 0..n-1 from the file with the given
+// path.
+func readPerm(path string, n int) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	s.Split(bufio.ScanWords)
+	var perm []int
+	for s.Scan() {
+		v, err := strconv.Atoi(s.Text())
+		if err != nil {
+			return nil, fmt.Errorf("column permutation: %v", err)
+		}
+		perm = append(perm, v)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(perm) != n {
+		return nil, fmt.Errorf("column permutation has %v entries, expected %v", len(perm), n)
+	}
+	return perm, nil
+}
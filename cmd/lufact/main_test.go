@@ -0,0 +1,242 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rwl/lufact/sparse"
+	"github.com/rwl/lufact/sparse/hb"
+	"github.com/rwl/lufact/sparse/mat"
+	"github.com/rwl/lufact/sparse/mm"
+	"github.com/rwl/lufact/sparse/npz"
+)
+
+const real3 = `%%MatrixMarket matrix coordinate real general
+3 3 6
+1 1 4
+2 1 1
+2 2 3
+3 2 -1
+1 3 2
+3 3 5
+`
+
+const complex2 = `%%MatrixMarket matrix coordinate complex general
+2 2 3
+1 1 2 1
+2 1 0 1
+2 2 3 0
+`
+
+// dense returns the elements of the 3 by 3 matrix a by rows.
+func dense(a *sparse.CSC) []float64 {
+	d := make([]float64, 9)
+	for j := 0; j < a.Cols; j++ {
+		for p := a.ColPtr[j]; p < a.ColPtr[j+1]; p++ {
+			d[a.RowInd[p]*3+j] += a.Values[p]
+		}
+	}
+	return d
+}
+
+func mul(a, b []float64) []float64 {
+	c := make([]float64, 9)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				c[i*3+j] += a[i*3+k] * b[k*3+j]
+			}
+		}
+	}
+	return c
+}
+
+func writeTemp(t *testing.T, dir, name, text string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(text), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lufact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a := writeTemp(t, dir, "a.mtx", real3)
+	z := writeTemp(t, dir, "z.mtx", complex2)
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"factor", "-pivot", "0.5", a}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if out := stdout.String(); !strings.Contains(out, "rank      3") || !strings.Contains(out, "nnz(A)    6") {
+		t.Errorf("factor output:\n%s", out)
+	}
+
+	for _, trans := range []string{"-trans=false", "-trans=true"} {
+		stdout.Reset()
+		if err := run([]string{"solve", trans, a}, &stdout, &stderr); err != nil {
+			t.Fatal(err)
+		}
+		x, err := mm.Read(&stdout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range x.Values {
+			if math.Abs(v-1) > 1e-14 {
+				t.Errorf("%s: x = %v, want ones", trans, x.Values)
+				break
+			}
+		}
+	}
+
+	// A given right-hand side with two columns, written to a file.
+	b := writeTemp(t, dir, "b.mtx", "%%MatrixMarket matrix array real general\n3 2\n6\n4\n4\n4\n1\n0\n")
+	out := filepath.Join(dir, "x.mtx")
+	if err := run([]string{"solve", "-rhs", b, "-o", out, a}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := mm.Read(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Rows != 3 || x.Cols != 2 || math.Abs(x.Values[0]-1) > 1e-14 || math.Abs(x.Values[3]-1) > 1e-14 {
+		t.Errorf("x = %+v", x)
+	}
+
+	// The right-hand sides of a Harwell-Boeing file.
+	hf := &hb.File{
+		Title: "real3", Key: "R3",
+		A: &sparse.CSC{
+			Rows: 3, Cols: 3,
+			ColPtr: []int{0, 2, 4, 6},
+			RowInd: []int{0, 1, 1, 2, 0, 2},
+			Values: []float64{4, 1, 3, -1, 2, 5},
+		},
+		RHS: &sparse.CSC{
+			Rows: 3, Cols: 1,
+			ColPtr: []int{0, 2},
+			RowInd: []int{1, 2},
+			Values: []float64{3, -1},
+		},
+	}
+	var buf bytes.Buffer
+	if err := hb.Write(&buf, hf); err != nil {
+		t.Fatal(err)
+	}
+	rua := writeTemp(t, dir, "a.rua", buf.String())
+	stdout.Reset()
+	if err := run([]string{"solve", rua}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if x, err = mm.Read(&stdout); err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{0, 1, 0}; math.Abs(x.Values[0]-want[0]) > 1e-14 || math.Abs(x.Values[1]-want[1]) > 1e-14 || math.Abs(x.Values[2]-want[2]) > 1e-14 {
+		t.Errorf("x = %v, want %v", x.Values, want)
+	}
+
+	// A SciPy archive, read by its extension and by its contents.
+	for _, name := range []string{"a.npz", "a.dat"} {
+		path := filepath.Join(dir, name)
+		if err := npz.WriteFile(path, hf.A); err != nil {
+			t.Fatal(err)
+		}
+		stdout.Reset()
+		if err := run([]string{"factor", path}, &stdout, &stderr); err != nil {
+			t.Fatal(err)
+		}
+		if out := stdout.String(); !strings.Contains(out, "rank      3") {
+			t.Errorf("%s factor output:\n%s", name, out)
+		}
+	}
+
+	// The factors written to a MAT-file, which is read back as input.
+	matFile := filepath.Join(dir, "lu.mat")
+	if err := run([]string{"factor", "-pivot", "0.1", "-mat", matFile, a}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	f, err = os.Open(matFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars, err := mat.Read(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 5 {
+		t.Fatalf("MAT-file has %d variables", len(vars))
+	}
+	m := make(map[string][]float64)
+	for _, v := range vars {
+		m[v.Name] = dense(v.A)
+	}
+	paq := mul(mul(m["P"], m["A"]), m["Q"])
+	lu := mul(m["L"], m["U"])
+	for k := range paq {
+		if math.Abs(paq[k]-lu[k]) > 1e-14 {
+			t.Errorf("PAQ = %v, LU = %v", paq, lu)
+			break
+		}
+	}
+	stdout.Reset()
+	if err := run([]string{"factor", matFile}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if out := stdout.String(); !strings.Contains(out, "nnz(A)    6") {
+		t.Errorf("MAT-file factor output:\n%s", out)
+	}
+
+	stdout.Reset()
+	if err := run([]string{"solve", z}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if x, err = mm.Read(&stdout); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range x.ZValues {
+		if cmplx.Abs(v-1) > 1e-14 {
+			t.Errorf("complex x = %v, want ones", x.ZValues)
+			break
+		}
+	}
+
+	stdout.Reset()
+	if err := run([]string{"bench", "-count", "2", "-wide", z}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "factorizations  2") {
+		t.Errorf("bench output:\n%s", stdout.String())
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"unknown", a},
+		{"factor"},
+		{"factor", "-format", "hb", a},
+		{"factor", filepath.Join(dir, "missing.mtx")},
+		{"solve", "-rhs", z, a},
+		{"bench", "-count", "0", a},
+	} {
+		if err := run(args, &stdout, &stderr); err == nil {
+			t.Errorf("expected error for %q", args)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package main
+
+import (
+	"errors"
+	"io"
+	"math"
+	"math/cmplx"
+
+	"github.com/rwl/lufact/gpd"
+	"github.com/rwl/lufact/gpz"
+	"github.com/rwl/lufact/sparse"
+	"github.com/rwl/lufact/sparse/mm"
+)
+
+// solver factorizes a real or complex matrix.
+type solver interface {
+	// factor computes the factorization, replacing any previous one.
+	factor() error
+
+	// stats returns the statistics of the factorization.
+	stats() (stats, error)
+
+	// factors returns the factors of PAQ = LU.
+	factors() (l, u, p, q *sparse.CSC, err error)
+
+	// solve solves for each column of b, or for A times a vector of
+	// ones if b is nil, writes the solutions to w if it is not nil and
+	// returns the largest relative residual.
+	solve(b *sparse.CSC, trans bool, w io.Writer) (float64, error)
+}
+
+type stats struct {
+	nnz     int
+	rank    int
+	resid   float64
+	dropped float64
+}
+
+func newSolver(a *sparse.CSC, f *factorFlags) (solver, error) {
+	d, z, err := f.options(a.Cols)
+	if err != nil {
+		return nil, err
+	}
+	if a.IsComplex() {
+		return &complexSolver{a: a, opts: z}, nil
+	}
+	return &realSolver{a: a, opts: d}, nil
+}
+
+type realSolver struct {
+	a    *sparse.CSC
+	opts []gpd.OptFunc
+	lu   *gpd.LU
+}
+
+func (s *realSolver) factor() (err error) {
+	s.lu, err = gpd.Factor(s.a.Cols, s.a.RowInd, s.a.ColPtr, s.a.Values, s.opts...)
+	return err
+}
+
+func (s *realSolver) stats() (stats, error) {
+	resid, dropped, err := s.lu.FactorResidual(s.a.RowInd, s.a.ColPtr, s.a.Values)
+	return stats{nnz: s.lu.NNZ(), rank: s.lu.Rank(), resid: resid, dropped: dropped}, err
+}
+
+func (s *realSolver) factors() (l, u, p, q *sparse.CSC, err error) {
+	f, err := s.lu.Factors()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	l = &sparse.CSC{Rows: f.N, Cols: f.N, ColPtr: f.LColPtr, RowInd: f.LRowInd, Values: f.LNZ}
+	u = &sparse.CSC{Rows: f.N, Cols: f.N, ColPtr: f.UColPtr, RowInd: f.URowInd, Values: f.UNZ}
+	p, q, err = permutations(f.RowPerm, f.ColPerm)
+	return l, u, p, q, err
+}
+
+func (s *realSolver) solve(b *sparse.CSC, trans bool, w io.Writer) (float64, error) {
+	a := s.a
+	n := a.Rows
+	var rhs [][]float64
+	if b == nil {
+		ones := make([]float64, n)
+		for i := range ones {
+			ones[i] = 1
+		}
+		rhs = [][]float64{make([]float64, n)}
+		matVec(a, ones, rhs[0], trans)
+	} else {
+		if b.IsComplex() {
+			return 0, errors.New("complex right-hand side for a real matrix")
+		}
+		for j := 0; j < b.Cols; j++ {
+			col := make([]float64, n)
+			for p := b.ColPtr[j]; p < b.ColPtr[j+1]; p++ {
+				col[b.RowInd[p]] += b.Values[p]
+			}
+			rhs = append(rhs, col)
+		}
+	}
+
+	x := make([][]float64, len(rhs))
+	for k := range rhs {
+		x[k] = append([]float64(nil), rhs[k]...)
+	}
+	if err := gpd.Solve(s.lu, x, trans); err != nil {
+		return 0, err
+	}
+
+	var resid float64
+	ax := make([]float64, n)
+	for k := range x {
+		matVec(a, x[k], ax, trans)
+		var r, nb float64
+		for i := range ax {
+			r = math.Hypot(r, rhs[k][i]-ax[i])
+			nb = math.Hypot(nb, rhs[k][i])
+		}
+		if nb != 0 {
+			r /= nb
+		}
+		resid = math.Max(resid, r)
+	}
+	if w == nil {
+		return resid, nil
+	}
+	out := make([]float64, 0, n*len(x))
+	for _, xk := range x {
+		out = append(out, xk...)
+	}
+	return resid, mm.WriteArray(w, n, len(x), out)
+}
+
+// permutations returns the permutation matrices P and Q of PAQ = LU,
+// given that row i of A is row rowPerm[i] of PAQ and column j of PAQ is
+// column colPerm[j] of A.
+func permutations(rowPerm, colPerm []int) (p, q *sparse.CSC, err error) {
+	if p, err = sparse.Permutation(rowPerm); err != nil {
+		return nil, nil, err
+	}
+	q, err = sparse.Permutation(colPerm)
+	return p, q, err
+}
+
+// matVec sets y to Ax, or to A'x if trans is true.
+func matVec(a *sparse.CSC, x, y []float64, trans bool) {
+	if !trans {
+		for i := range y {
+			y[i] = 0
+		}
+	}
+	for j := 0; j < a.Cols; j++ {
+		var t float64
+		for p := a.ColPtr[j]; p < a.ColPtr[j+1]; p++ {
+			if trans {
+				t += a.Values[p] * x[a.RowInd[p]]
+			} else {
+				y[a.RowInd[p]] += a.Values[p] * x[j]
+			}
+		}
+		if trans {
+			y[j] = t
+		}
+	}
+}
+
+type complexSolver struct {
+	a    *sparse.CSC
+	opts []gpz.OptFunc
+	lu   *gpz.LU
+}
+
+func (s *complexSolver) factor() (err error) {
+	s.lu, err = gpz.Factor(s.a.Cols, s.a.RowInd, s.a.ColPtr, s.a.ZValues, s.opts...)
+	return err
+}
+
+func (s *complexSolver) stats() (stats, error) {
+	resid, dropped, err := s.lu.FactorResidual(s.a.RowInd, s.a.ColPtr, s.a.ZValues)
+	return stats{nnz: s.lu.NNZ(), rank: s.lu.Rank(), resid: resid, dropped: dropped}, err
+}
+
+func (s *complexSolver) factors() (l, u, p, q *sparse.CSC, err error) {
+	f, err := s.lu.Factors()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	l = &sparse.CSC{Rows: f.N, Cols: f.N, ColPtr: f.LColPtr, RowInd: f.LRowInd, ZValues: f.LNZ}
+	u = &sparse.CSC{Rows: f.N, Cols: f.N, ColPtr: f.UColPtr, RowInd: f.URowInd, ZValues: f.UNZ}
+	p, q, err = permutations(f.RowPerm, f.ColPerm)
+	return l, u, p, q, err
+}
+
+func (s *complexSolver) solve(b *sparse.CSC, trans bool, w io.Writer) (float64, error) {
+	a := s.a
+	n := a.Rows
+	var rhs [][]complex128
+	if b == nil {
+		ones := make([]complex128, n)
+		for i := range ones {
+			ones[i] = 1
+		}
+		rhs = [][]complex128{make([]complex128, n)}
+		zmatVec(a, ones, rhs[0], trans)
+	} else {
+		for j := 0; j < b.Cols; j++ {
+			col := make([]complex128, n)
+			for p := b.ColPtr[j]; p < b.ColPtr[j+1]; p++ {
+				if b.IsComplex() {
+					col[b.RowInd[p]] += b.ZValues[p]
+				} else {
+					col[b.RowInd[p]] += complex(b.Values[p], 0)
+				}
+			}
+			rhs = append(rhs, col)
+		}
+	}
+
+	x := make([][]complex128, len(rhs))
+	for k := range rhs {
+		x[k] = append([]complex128(nil), rhs[k]...)
+	}
+	if err := gpz.Solve(s.lu, x, trans); err != nil {
+		return 0, err
+	}
+
+	var resid float64
+	ax := make([]complex128, n)
+	for k := range x {
+		zmatVec(a, x[k], ax, trans)
+		var r, nb float64
+		for i := range ax {
+			r = math.Hypot(r, cmplx.Abs(rhs[k][i]-ax[i]))
+			nb = math.Hypot(nb, cmplx.Abs(rhs[k][i]))
+		}
+		if nb != 0 {
+			r /= nb
+		}
+		resid = math.Max(resid, r)
+	}
+	if w == nil {
+		return resid, nil
+	}
+	out := make([]complex128, 0, n*len(x))
+	for _, xk := range x {
+		out = append(out, xk...)
+	}
+	return resid, mm.WriteZArray(w, n, len(x), out)
+}
+
+// zmatVec sets y to Ax, or to A'x, not conjugated, if trans is true.
+func zmatVec(a *sparse.CSC, x, y []complex128, trans bool) {
+	if !trans {
+		for i := range y {
+			y[i] = 0
+		}
+	}
+	for j := 0; j < a.Cols; j++ {
+		var t complex128
+		for p := a.ColPtr[j]; p < a.ColPtr[j+1]; p++ {
+			if trans {
+				t += a.ZValues[p] * x[a.RowInd[p]]
+			} else {
+				y[a.RowInd[p]] += a.ZValues[p] * x[j]
+			}
+		}
+		if trans {
+			y[j] = t
+		}
+	}
+}
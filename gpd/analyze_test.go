@@ -0,0 +1,92 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestAnalyze(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+	m := 20
+	grow, gcol, gnz := csc(laplacian(m))
+
+	for _, test := range []struct {
+		name          string
+		n             int
+		rowind, colst []int
+		nzA           []float64
+		opts          []gp.OptFunc
+		exact         bool
+	}{
+		{"lhr01", n, rowind, colst, nzA, nil, false},
+		{"lhr01 threshold", n, rowind, colst, nzA, []gp.OptFunc{gp.PartialPivoting(0.1)}, false},
+		{"laplacian", m * m, grow, gcol, gnz, nil, false},
+		{"laplacian without pivoting", m * m, grow, gcol, gnz, []gp.OptFunc{gp.WithoutPivoting()}, true},
+	} {
+		an, err := gp.Analyze(test.n, test.rowind, test.colst, test.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if an.Exact() != test.exact {
+			t.Errorf("%s: exact expected %v actual %v", test.name, test.exact, an.Exact())
+		}
+
+		// The analysis is reused for matrices with the same pattern.
+		for _, scale := range []float64{1, 2} {
+			nz := make([]float64, len(test.nzA))
+			for i, v := range test.nzA {
+				nz[i] = scale * v
+			}
+			var log bytes.Buffer
+			logger := gp.Logger
+			gp.Logger = &log
+			opts := append([]gp.OptFunc{gp.FillRatio(1), gp.WithAnalysis(an)}, test.opts...)
+			lu, err := gp.Factor(test.n, test.rowind, test.colst, nz, opts...)
+			gp.Logger = logger
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			if strings.Contains(log.String(), "expanding") {
+				t.Errorf("%s: storage expanded", test.name)
+			}
+			if an.Exact() && lu.NNZ() != an.NNZ() {
+				t.Errorf("%s: nnz expected %v actual %v", test.name, an.NNZ(), lu.NNZ())
+			}
+			if lu.NNZ() > an.NNZ() {
+				t.Errorf("%s: nnz %v exceeds bound %v", test.name, lu.NNZ(), an.NNZ())
+			}
+
+			x0 := make([]float64, test.n)
+			for i := range x0 {
+				x0[i] = float64(i%5) + 1
+			}
+			b := matVec(test.n, test.rowind, test.colst, nz, x0)
+			if err := gp.Solve(lu, [][]float64{b}, false); err != nil {
+				t.Fatal(err)
+			}
+			for i := range b {
+				if math.Abs(b[i]-x0[i]) > 1e-8*math.Abs(x0[i]) {
+					t.Fatalf("%s: x[%d] expected %v actual %v", test.name, i, x0[i], b[i])
+				}
+			}
+		}
+	}
+
+	an, err := gp.Analyze(m*m, grow, gcol, gp.WithoutPivoting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gp.Factor(n, rowind, colst, nzA, gp.WithAnalysis(an)); err == nil {
+		t.Error("expected error for analysis of a different pattern")
+	}
+	if _, err := gp.Factor(m*m, grow, gcol, gnz, gp.WithAnalysis(an)); err == nil {
+		t.Error("expected error for exact analysis with pivoting")
+	}
+}
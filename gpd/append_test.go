@@ -0,0 +1,130 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"math"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestAppendColumn(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+
+	lu, err := gp.NewLU(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < n; j++ {
+		start, end := colst[j], colst[j+1]
+		if err := lu.AppendColumn(rowind[start:end], nzA[start:end]); err != nil {
+			t.Fatalf("append[%d]: %v", j, err)
+		}
+		if lu.Cols() != j+1 {
+			t.Fatalf("cols, expected %v actual %v", j+1, lu.Cols())
+		}
+	}
+	if err := lu.AppendColumn([]int{0}, []float64{1}); err == nil {
+		t.Errorf("expected error appending to complete factorization")
+	}
+
+	x0 := make([]float64, n)
+	for i := range x0 {
+		x0[i] = 1
+	}
+	b := matVec(n, rowind, colst, nzA, x0)
+
+	if err := gp.Solve(lu, [][]float64{b}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	const eps = 1e-10
+
+	resid := residual(b)
+	if resid > eps {
+		t.Fatalf("resid, expected < %v actual %v", eps, resid)
+	}
+}
+
+func TestAppendColumnDependent(t *testing.T) {
+	lu, err := gp.NewLU(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lu.AppendColumn([]int{0, 1}, []float64{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lu.AppendColumn([]int{0, 1}, []float64{2, 4}); err == nil {
+		t.Fatalf("expected error for dependent column")
+	}
+	if lu.Cols() != 1 {
+		t.Fatalf("cols, expected 1 actual %v", lu.Cols())
+	}
+	if err := gp.Solve(lu, [][]float64{{1, 1, 1}}, false); err == nil {
+		t.Errorf("expected error solving incomplete factorization")
+	}
+	if err := lu.AppendColumn([]int{1, 2}, []float64{1, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lu.AppendColumn([]int{2}, []float64{3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A = [1 0 0; 2 1 0; 0 1 3]
+	b := []float64{1, 3, 4}
+	if err := gp.Solve(lu, [][]float64{b}, false); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range b {
+		if math.Abs(v-1) > 1e-14 {
+			t.Errorf("x[%d], expected 1 actual %v", i, v)
+		}
+	}
+}
+
+func TestAppendColumnZeroPivot(t *testing.T) {
+	lu, err := gp.NewLU(2, gp.WithoutPivoting())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, col := range []struct {
+		rowind []int
+		vals   []float64
+	}{
+		{[]int{1}, []float64{1}},       // structurally zero diagonal
+		{[]int{0, 1}, []float64{0, 1}}, // numerically zero diagonal
+	} {
+		err := lu.AppendColumn(col.rowind, col.vals)
+		if err == nil {
+			t.Fatalf("expected error for zero pivot")
+		}
+		if msg := "zero pivot in column 0"; err.Error() != msg {
+			t.Errorf("error, expected %q actual %q", msg, err)
+		}
+		if lu.Cols() != 0 {
+			t.Fatalf("cols, expected 0 actual %v", lu.Cols())
+		}
+	}
+	if err := lu.AppendColumn([]int{0, 1}, []float64{2, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lu.AppendColumn([]int{1}, []float64{3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := lu.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A = [2 0; 1 3]
+	b := []float64{2, 4}
+	if err := gp.Solve(lu, [][]float64{b}, false); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range b {
+		if math.Abs(v-1) > 1e-14 {
+			t.Errorf("x[%d], expected 1 actual %v", i, v)
+		}
+	}
+}
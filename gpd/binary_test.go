@@ -0,0 +1,186 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+	rowindR, colptrR, nzR := csc([][]float64{
+		{4, 1, 6, 0, 4},
+		{1, 3, 7, 1, 2},
+		{0, 1, 2, 0, 0},
+		{2, 0, 2, 5, 7},
+		{0, 2, 4, 1, 1},
+	})
+
+	for _, test := range []struct {
+		name   string
+		n      int
+		rowind []int
+		colst  []int
+		nzA    []float64
+		opts   []gp.OptFunc
+	}{
+		{"compact", n, rowind, colst, nzA, nil},
+		{"wide", n, rowind, colst, nzA, []gp.OptFunc{gp.WideIndexes()}},
+		{"dense", n, rowind, colst, nzA, []gp.OptFunc{gp.DenseThreshold(0.05)}},
+		{"drop", n, rowind, colst, nzA, []gp.OptFunc{gp.DropThreshold(1e-4)}},
+		{"rank", 5, rowindR, colptrR, nzR, []gp.OptFunc{gp.RankDeficient(1e-12)}},
+	} {
+		lu, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA, test.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		data, err := lu.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		var got gp.LU
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got.NNZ() != lu.NNZ() || got.Rank() != lu.Rank() {
+			t.Errorf("%s: nnz %v rank %v expected %v %v", test.name, got.NNZ(), got.Rank(), lu.NNZ(), lu.Rank())
+		}
+		again, err := got.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !bytes.Equal(again, data) {
+			t.Errorf("%s: encoding of decoded factorization differs", test.name)
+		}
+
+		for _, trans := range []bool{false, true} {
+			want := make([]float64, test.n)
+			for i := range want {
+				want[i] = float64(i%3) + 1
+			}
+			x := append([]float64(nil), want...)
+			if err := gp.Solve(lu, [][]float64{want}, trans); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			if err := gp.Solve(&got, [][]float64{x}, trans); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			for i := range x {
+				if x[i] != want[i] {
+					t.Fatalf("%s: trans=%v x[%d] expected %v actual %v", test.name, trans, i, want[i], x[i])
+				}
+			}
+		}
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+	var buf bytes.Buffer
+	var written int64
+	for _, opts := range [][]gp.OptFunc{nil, {gp.WideIndexes()}} {
+		lu, err := gp.Factor(n, rowind, colst, nzA, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := lu.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		written += w
+	}
+	if int64(buf.Len()) != written {
+		t.Fatalf("written %v bytes, buffer has %v", written, buf.Len())
+	}
+	data := append([]byte(nil), buf.Bytes()...)
+
+	// Two factorizations are read from the same stream.
+	var read int64
+	for i := 0; i < 2; i++ {
+		var lu gp.LU
+		r, err := lu.ReadFrom(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read += r
+	}
+	if read != written || buf.Len() != 0 {
+		t.Errorf("read %v of %v bytes, %v remain", read, written, buf.Len())
+	}
+
+	var lu gp.LU
+	if _, err := lu.ReadFrom(bytes.NewReader(data[:len(data)/4])); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated stream: expected %v actual %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestUnmarshalBinaryCorrupt(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+	lu, err := gp.Factor(n, rowind, colst, nzA, gp.WideIndexes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := lu.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resum := func(b []byte) {
+		binary.LittleEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+	}
+
+	for _, test := range []struct {
+		name    string
+		corrupt func(b []byte) []byte
+	}{
+		{"magic", func(b []byte) []byte {
+			b[0] = 'X'
+			return b
+		}},
+		{"version", func(b []byte) []byte {
+			b[4] = 99
+			return b
+		}},
+		{"scalar", func(b []byte) []byte {
+			b[6] = 'z'
+			return b
+		}},
+		{"truncated", func(b []byte) []byte {
+			return b[:len(b)-1]
+		}},
+		{"checksum", func(b []byte) []byte {
+			b[len(b)/2] ^= 1
+			return b
+		}},
+		{"permutation", func(b []byte) []byte {
+			// The last entry of the column permutation precedes the
+			// checksum.
+			binary.LittleEndian.PutUint64(b[len(b)-12:], 0)
+			resum(b)
+			return b
+		}},
+		{"row index", func(b []byte) []byte {
+			// The row indexes follow the header and the values.
+			off := len(b) - 4 - 8*(4*n+1) - 8
+			binary.LittleEndian.PutUint64(b[off:], uint64(n+1))
+			resum(b)
+			return b
+		}},
+	} {
+		b := test.corrupt(append([]byte(nil), data...))
+		var got gp.LU
+		if err := got.UnmarshalBinary(b); err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+
+	if _, err := new(gp.LU).MarshalBinary(); err == nil {
+		t.Errorf("expected error for an empty factorization")
+	}
+}
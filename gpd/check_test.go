@@ -0,0 +1,128 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestCheck(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+
+	for _, test := range []struct {
+		name string
+		opts []gp.OptFunc
+	}{
+		{"compact", nil},
+		{"expand", []gp.OptFunc{gp.ExpandRatio(2)}},
+		{"wide", []gp.OptFunc{gp.WideIndexes()}},
+		{"threshold", []gp.OptFunc{gp.PartialPivoting(0.1)}},
+		{"drop", []gp.OptFunc{gp.DropThreshold(1e-3)}},
+		{"dense", []gp.OptFunc{gp.DenseThreshold(0.05)}},
+		{"supernodal", []gp.OptFunc{gp.Supernodal()}},
+		{"parallel", []gp.OptFunc{gp.Parallel(2)}},
+		{"shift", []gp.OptFunc{gp.DiagonalShift(1e-3, 0)}},
+	} {
+		lu, err := gp.Factor(n, rowind, colst, nzA, test.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := lu.Check(); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+
+	ilut, err := gp.NewILUT(n, rowind, colst, nzA, 1e-4, 50, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ilut.LU().Check(); err != nil {
+		t.Errorf("ILUT: %v", err)
+	}
+	rowindL, colptrL, nzL := csc(laplacian(6))
+	iluk, err := gp.NewILUK(36, rowindL, colptrL, nzL, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := iluk.LU().Check(); err != nil {
+		t.Errorf("ILUK: %v", err)
+	}
+
+	inc, err := gp.NewLU(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inc.Check(); err == nil {
+		t.Errorf("expected error for an incomplete factorization")
+	}
+	for j := 0; j < n; j++ {
+		if err := inc.AppendColumn(rowind[colst[j]:colst[j+1]], nzA[colst[j]:colst[j+1]]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := inc.Check(); err != nil {
+		t.Errorf("AppendColumn: %v", err)
+	}
+
+	rowindR, colptrR, nzR := csc([][]float64{
+		{4, 1, 6, 0, 4},
+		{1, 3, 7, 1, 2},
+		{0, 1, 2, 0, 0},
+		{2, 0, 2, 5, 7},
+		{0, 2, 4, 1, 1},
+	})
+	rank, err := gp.Factor(5, rowindR, colptrR, nzR, gp.RankDeficient(1e-12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rank.Check(); err != nil {
+		t.Errorf("rank-deficient: %v", err)
+	}
+}
+
+func TestCheckCorrupt(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+	lu, err := gp.Factor(n, rowind, colst, nzA, gp.WideIndexes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := lu.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The values and then the row indexes of L and U follow the header.
+	// The first column of U holds only its diagonal element, followed
+	// by the first column of L.
+	nnz := lu.NNZ()
+	values := len(data) - 4 - 8*(4*n+1) - 16*nnz
+	rows := values + 8*nnz
+
+	for _, test := range []struct {
+		name    string
+		corrupt func(b []byte)
+	}{
+		{"zero diagonal", func(b []byte) {
+			binary.LittleEndian.PutUint64(b[values:], 0)
+		}},
+		{"diagonal row", func(b []byte) {
+			binary.LittleEndian.PutUint64(b[rows:], 2)
+		}},
+		{"row of L", func(b []byte) {
+			binary.LittleEndian.PutUint64(b[rows+8:], 1)
+		}},
+	} {
+		b := append([]byte(nil), data...)
+		test.corrupt(b)
+		binary.LittleEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+		var got gp.LU
+		if err := got.UnmarshalBinary(b); err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
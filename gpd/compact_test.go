@@ -0,0 +1,123 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"strconv"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestWideIndexes(t *testing.T) {
+	for _, test := range supernodeMatrices(20, 6, 300) {
+		wide, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA, gp.WideIndexes())
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		lu, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if lu.NNZ() != wide.NNZ() {
+			t.Errorf("%s: nnz expected %d actual %d", test.name, wide.NNZ(), lu.NNZ())
+		}
+
+		b := make([]float64, test.n)
+		for i := range b {
+			b[i] = float64(i%5) - 2
+		}
+		solves := []struct {
+			name  string
+			solve func(lu *gp.LU, x []float64) error
+		}{
+			{"Solve", func(lu *gp.LU, x []float64) error {
+				return gp.Solve(lu, [][]float64{x}, false)
+			}},
+			{"SolveTrans", func(lu *gp.LU, x []float64) error {
+				return gp.Solve(lu, [][]float64{x}, true)
+			}},
+			{"SolveMany", func(lu *gp.LU, x []float64) error {
+				return gp.SolveMany(lu, x, 1, false, 2)
+			}},
+			{"SolveLU", func(lu *gp.LU, x []float64) error {
+				if err := lu.SolveL(x, x); err != nil {
+					return err
+				}
+				return lu.SolveU(x, x)
+			}},
+			{"SolveUTLT", func(lu *gp.LU, x []float64) error {
+				if err := lu.SolveUT(x, x); err != nil {
+					return err
+				}
+				return lu.SolveLT(x, x)
+			}},
+		}
+		check := func(when string, solves []struct {
+			name  string
+			solve func(lu *gp.LU, x []float64) error
+		}) {
+			for _, s := range solves {
+				want := append([]float64(nil), b...)
+				if err := s.solve(wide, want); err != nil {
+					t.Fatalf("%s: %s %s: %v", test.name, when, s.name, err)
+				}
+				x := append([]float64(nil), b...)
+				if err := s.solve(lu, x); err != nil {
+					t.Fatalf("%s: %s %s: %v", test.name, when, s.name, err)
+				}
+				for i := range x {
+					if x[i] != want[i] {
+						t.Fatalf("%s: %s %s: x[%d] expected %v actual %v", test.name, when, s.name, i, want[i], x[i])
+					}
+				}
+			}
+		}
+		check("compact", solves)
+
+		// Replacing a column with itself widens the indexes. The
+		// split solves do not support updates.
+		k := test.n / 2
+		rowind := test.rowind[test.colst[k]:test.colst[k+1]]
+		vals := test.nzA[test.colst[k]:test.colst[k+1]]
+		for _, f := range []*gp.LU{wide, lu} {
+			if err := f.ReplaceColumn(k, rowind, vals); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+		check("updated", solves[:3])
+	}
+}
+
+func BenchmarkSolve(b *testing.B) {
+	for _, test := range supernodeMatrices(100, 18, 5000) {
+		for _, bench := range []struct {
+			name  string
+			index int
+			opts  []gp.OptFunc
+		}{
+			{"int", strconv.IntSize / 8, []gp.OptFunc{gp.WideIndexes()}},
+			{"int32", 4, nil},
+		} {
+			lu, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA, bench.opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			x := make([]float64, test.n)
+			b.Run(test.name+"/"+bench.name, func(b *testing.B) {
+				// The values and row indexes of L and U, the column
+				// pointers and the permutations are read once.
+				b.SetBytes(int64(lu.NNZ()*(8+bench.index) + 4*test.n*bench.index))
+				for i := 0; i < b.N; i++ {
+					for j := range x {
+						x[j] = 1
+					}
+					if err := lu.Solve(x, x); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"math"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestDenseThreshold(t *testing.T) {
+	for _, test := range supernodeMatrices(20, 8, 500) {
+		x0 := make([]float64, test.n)
+		for i := range x0 {
+			x0[i] = float64(i%7) + 1
+		}
+
+		for _, density := range []float64{0.05, 0.5} {
+			lu, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA, gp.DenseThreshold(density))
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			for _, trans := range []bool{false, true} {
+				var b []float64
+				if trans {
+					b = matTransVec(test.n, test.rowind, test.colst, test.nzA, x0)
+				} else {
+					b = matVec(test.n, test.rowind, test.colst, test.nzA, x0)
+				}
+				if err := gp.Solve(lu, [][]float64{b}, trans); err != nil {
+					t.Fatal(err)
+				}
+				for i := range b {
+					if math.Abs(b[i]-x0[i]) > 1e-8*math.Abs(x0[i]) {
+						t.Fatalf("%s: density %v: trans %v: x[%d] expected %v actual %v",
+							test.name, density, trans, i, x0[i], b[i])
+					}
+				}
+			}
+
+			// The split solves are not available for a dense
+			// trailing submatrix.
+			x := make([]float64, test.n)
+			if density == 0.05 && lu.SolveL(x, x) == nil {
+				t.Errorf("%s: density %v: expected dense trailing submatrix", test.name, density)
+			}
+		}
+	}
+
+	if _, err := gp.Factor(1, []int{0}, []int{0, 1}, []float64{1}, gp.DenseThreshold(0)); err == nil {
+		t.Error("expected error for zero density")
+	}
+}
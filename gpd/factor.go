@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"strconv"
 )
 
 // Logger is a writer used for logging messages.
@@ -32,6 +33,18 @@ type options struct {
 	fillRatio      float64
 	expandRatio    float64
 	colPerm        []int
+	rankDeficient  bool
+	rankTol        float64
+	drop           *dropRule
+	modified       bool
+	shift          float64
+	shiftTiny      float64
+	workers        int
+	supernodal     bool
+	denseThreshold float64
+	analysis       *Analysis
+	maxLUNZ        int
+	wideIndexes    bool
 }
 
 func (opts *options) String() string {
@@ -132,6 +145,171 @@ func ColPerm(colPerm []int) OptFunc {
 	}
 }
 
+// RankDeficient enables rank-revealing factorization. A column whose
+// largest pivot candidate is no greater than tol times the largest
+// magnitude in the corresponding column of A is deferred to the end
+// of the column ordering instead of causing Factor to fail.
+func RankDeficient(tol float64) OptFunc {
+	return func(opts *options) error {
+		if tol < 0 {
+			return fmt.Errorf("rank tolerance (%v) must be >= 0", tol)
+		}
+		opts.rankDeficient = true
+		opts.rankTol = tol
+		return nil
+	}
+}
+
+// Modified adds the elements dropped from each column of L and U to
+// its diagonal element (modified incomplete LU), so that the column
+// sums of LU equal those of PAQ.
+func Modified() OptFunc {
+	return func(opts *options) error {
+		opts.modified = true
+		return nil
+	}
+}
+
+// DiagonalShift enables breakdown recovery. If a pivot is zero or has
+// magnitude no greater than tiny times the 2-norm of its column of A,
+// the factorization of A + alpha*diag(A) is computed instead, doubling
+// alpha until the pivots are acceptable. The shift that was used is
+// returned by LU.Shift.
+func DiagonalShift(alpha, tiny float64) OptFunc {
+	return func(opts *options) error {
+		if alpha <= 0 {
+			return fmt.Errorf("diagonal shift (%v) must be > 0", alpha)
+		}
+		if tiny < 0 {
+			return fmt.Errorf("tiny pivot tolerance (%v) must be >= 0", tiny)
+		}
+		opts.shift = alpha
+		opts.shiftTiny = tiny
+		return nil
+	}
+}
+
+// Parallel computes the columns of the factorization concurrently,
+// using up to workers goroutines, or GOMAXPROCS if workers < 1. A
+// column is computed once its descendants in the column elimination
+// tree of A'A are complete, so independent subtrees are factored
+// concurrently. The factors are identical to those computed serially,
+// but storage is allocated for the bound on fill given by the Cholesky
+// factor of A'A. If that storage would exceed MaxLUNonzeros, the
+// columns are computed serially. Parallel may not be used with
+// RankDeficient and is ignored by FactorPartial.
+func Parallel(workers int) OptFunc {
+	return func(opts *options) error {
+		opts.workers = workers
+		return nil
+	}
+}
+
+// Supernodal enables supernodal updates. Consecutive columns of L with
+// the same structure are kept as dense panels and the update of each
+// column uses dense kernels for them. The factors are equal to those
+// computed without supernodes up to rounding error. Supernodal is
+// ignored with a drop rule and by a Parallel factorization.
+func Supernodal() OptFunc {
+	return func(opts *options) error {
+		opts.supernodal = true
+		return nil
+	}
+}
+
+// DenseThreshold switches to a dense LU factorization, with partial
+// pivoting, for the trailing submatrix once the fraction of nonzeros
+// in the column of L just computed exceeds density and at least
+// minDenseTrailing columns remain. Solve uses the dense factors of the
+// trailing submatrix in place of its columns of L and U. DenseThreshold
+// is ignored with RankDeficient or a drop rule and by FactorPartial
+// and a Parallel factorization.
+func DenseThreshold(density float64) OptFunc {
+	return func(opts *options) error {
+		if density <= 0 || density > 1 {
+			return fmt.Errorf("density (%v) must be in (0,1]", density)
+		}
+		opts.denseThreshold = density
+		return nil
+	}
+}
+
+// WithAnalysis allocates the storage for L and U from an analysis of
+// the sparsity pattern of A by Analyze, instead of using FillRatio, so
+// that it need not be expanded during factorization. The analysis must
+// be for the same pattern, column permutation and, if it is Exact,
+// without pivoting. The storage may still be expanded by RankDeficient.
+func WithAnalysis(an *Analysis) OptFunc {
+	return func(opts *options) error {
+		opts.analysis = an
+		return nil
+	}
+}
+
+// MaxLUNonzeros limits the storage for L and U to n nonzeros. If the
+// storage would have to grow beyond the limit, the factorization fails
+// with a *MemoryLimitError. Room for the fill of a column is reserved
+// before it is computed, so a factorization with nnz nonzeros in L and
+// U needs a limit of about nnz plus the order of the matrix.
+func MaxLUNonzeros(n int) OptFunc {
+	return func(opts *options) error {
+		if n <= 0 {
+			return fmt.Errorf("nonzero limit (%v) must be > 0", n)
+		}
+		if opts.maxLUNZ == 0 || n < opts.maxLUNZ {
+			opts.maxLUNZ = n
+		}
+		return nil
+	}
+}
+
+// luEntryBytes is the size of a value and its row index in the storage
+// for L and U.
+const luEntryBytes = 8 + strconv.IntSize/8
+
+// MaxMemory limits the storage for the values and row indexes of L and
+// U to the given number of bytes, as for MaxLUNonzeros.
+func MaxMemory(bytes int64) OptFunc {
+	if bytes < luEntryBytes {
+		return func(opts *options) error {
+			return fmt.Errorf("memory limit (%v bytes) must be >= %v bytes", bytes, luEntryBytes)
+		}
+	}
+	return MaxLUNonzeros(int(bytes / luEntryBytes))
+}
+
+// WideIndexes keeps the index arrays of the factorization as int. By
+// default Factor stores them as int32 once a complete, nonsingular
+// factorization is computed, if the order and the number of nonzeros
+// fit, which reduces the memory used and read by Solve. The arrays are
+// widened again by ReplaceColumn and AnalyzeSolve.
+func WideIndexes() OptFunc {
+	return func(opts *options) error {
+		opts.wideIndexes = true
+		return nil
+	}
+}
+
+// newOptions returns the default options modified by optFuncs.
+func newOptions(optFuncs []OptFunc) (*options, error) {
+	opts := &options{
+		pivotPolicy:    partialPivoting,
+		pivotThreshold: 1,
+		dropThreshold:  0,  // do not drop
+		colFillRatio:   -1, // do not limit column fill ratio
+		fillRatio:      4,
+		expandRatio:    1.2,
+		workers:        1,
+	}
+	for _, optionFunc := range optFuncs {
+		err := optionFunc(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
 // LU is a lower-upper numeric factorization.
 type LU struct {
 	luSize   int
@@ -143,7 +321,102 @@ type LU struct {
 	rowPerm []int
 	colPerm []int
 
-	nA int
+	nA   int
+	nCol int
+	rank int
+
+	// dropped is the sum of squares of the magnitudes of the elements
+	// dropped during factorization.
+	dropped float64
+
+	// shift is the diagonal shift alpha of a factorization of
+	// A + alpha*diag(A) computed by DiagonalShift.
+	shift float64
+
+	// inc holds the state of a factorization built by AppendColumn.
+	inc *incremental
+
+	// upd holds the modifications made by ReplaceColumn.
+	upd *update
+
+	// sched holds the level sets computed by AnalyzeSolve.
+	sched *levelSchedule
+
+	// dense holds the factors of the trailing submatrix if the
+	// factorization switched to dense, see DenseThreshold.
+	dense *denseTrailing
+
+	// maxSize is the limit on luSize, or zero.
+	maxSize int
+
+	// opts holds the options used to compute the factorization.
+	opts *options
+
+	// c32 holds the index arrays in place of luRowInd, lColPtr,
+	// uColPtr, rowPerm and colPerm if they have been compacted.
+	c32 *compactIndex
+}
+
+// MemoryLimitError is returned when the storage for L and U would grow
+// beyond the limit set by MaxLUNonzeros or MaxMemory.
+type MemoryLimitError struct {
+	// Column is the index, in the column order of the factorization,
+	// of the column being computed.
+	Column int
+
+	// NNZ is the number of nonzeros stored in L and U and Limit is the
+	// limit on the storage.
+	NNZ   int
+	Limit int
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("LU storage limit of %d nonzeros reached at column %d with %d nonzeros",
+		e.Limit, e.Column, e.NNZ)
+}
+
+// expand grows the LU storage by the given ratio so that there is room
+// for a column of jcol after lastlu.
+func (lu *LU) expand(expandRatio float64, lastlu, jcol int) error {
+	newSize := int(float64(lu.luSize) * expandRatio)
+	if newSize <= lastlu+lu.nA {
+		newSize = lastlu + lu.nA + 1
+	}
+	if lu.maxSize > 0 && newSize > lu.maxSize {
+		if lu.maxSize <= lastlu+lu.nA {
+			return &MemoryLimitError{Column: jcol - 1, NNZ: lastlu, Limit: lu.maxSize}
+		}
+		newSize = lu.maxSize
+	}
+
+	if Logger != nil {
+		fmt.Fprintf(Logger, "expanding LU to %d nonzeros\n", newSize)
+	}
+
+	luNZ := make([]float64, newSize)
+	copy(luNZ, lu.luNZ)
+	lu.luNZ = luNZ
+	//lu.luNZ = append(lu.luNZ, make([]float64, newSize-lu.luSize)...)
+
+	luRowInd := make([]int, newSize)
+	copy(luRowInd, lu.luRowInd)
+	lu.luRowInd = luRowInd
+	//lu.luRowInd = append(lu.luRowInd, make([]int, newSize-lu.luSize)...)
+
+	lu.luSize = newSize
+	return nil
+}
+
+// trim drops the storage after lastlu.
+func (lu *LU) trim(lastlu int) {
+	if lastlu == lu.luSize {
+		return
+	}
+	luNZ := make([]float64, lastlu)
+	copy(luNZ, lu.luNZ)
+	luRowInd := make([]int, lastlu)
+	copy(luRowInd, lu.luRowInd)
+	lu.luNZ, lu.luRowInd, lu.luSize = luNZ, luRowInd, lastlu
 }
 
 // Factor performs sparse LU factorization with partial pivoting.
@@ -154,34 +427,51 @@ type LU struct {
 // are returned.  This subroutine uses the Coleman-Gilbert-Peierls
 // algorithm, in which total time is O(nonzero multiplications).
 func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*LU, error) {
+	opts, err := newOptions(optFuncs)
+	if err != nil {
+		return nil, err
+	}
+	var lu *LU
+	if opts.shift != 0 {
+		lu, err = factorShifted(nA, rowind, colptr, nzA, opts, optFuncs)
+	} else {
+		lu, _, err = factor(nA, rowind, colptr, nzA, nA, false, optFuncs)
+	}
+	if err != nil {
+		return lu, err
+	}
+	if !opts.wideIndexes {
+		lu.compact()
+	}
+	return lu, nil
+}
+
+// factor computes the first k columns of the factorization. If partial
+// is true the Schur complement of the remaining columns is returned,
+// otherwise the columns deferred by a rank-deficient factorization are
+// completed.
+func factor(nA int, rowind, colptr []int, nzA []float64, k int, partial bool, optFuncs []OptFunc) (*LU, *Schur, error) {
 	var (
 		nrow = nA
 		ncol = nA
 		nnzA = len(nzA)
 	)
 	if nnzA > nA*nA {
-		return nil, fmt.Errorf("nnz (%v) must be < n*n (%v)", nnzA, nA*nA)
+		return nil, nil, fmt.Errorf("nnz (%v) must be < n*n (%v)", nnzA, nA*nA)
 	}
 	if len(rowind) != len(nzA) {
-		return nil, fmt.Errorf("len rowind (%v) must be nnz (%v)", len(rowind), len(nzA))
+		return nil, nil, fmt.Errorf("len rowind (%v) must be nnz (%v)", len(rowind), len(nzA))
 	}
 	if len(colptr) != ncol+1 {
-		return nil, fmt.Errorf("len colptr (%v) must be ncol+1 (%v)", len(colptr), ncol+1)
+		return nil, nil, fmt.Errorf("len colptr (%v) must be ncol+1 (%v)", len(colptr), ncol+1)
 	}
-
-	opts := &options{
-		pivotPolicy:    partialPivoting,
-		pivotThreshold: 1,
-		dropThreshold:  0,  // do not drop
-		colFillRatio:   -1, // do not limit column fill ratio
-		fillRatio:      4,
-		expandRatio:    1.2,
+	if k < 0 || k > ncol {
+		return nil, nil, fmt.Errorf("k (%v) out of range [0,%d]", k, ncol)
 	}
-	for _, optionFunc := range optFuncs {
-		err := optionFunc(opts)
-		if err != nil {
-			return nil, err
-		}
+
+	opts, err := newOptions(optFuncs)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	if Logger != nil {
@@ -193,11 +483,11 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 		if len(opts.colPerm) != ncol {
 			//*info = -1
 			//goto free_and_exit
-			return nil, fmt.Errorf("column permutation (%v) must be a length ncol %v", len(opts.colPerm), ncol)
+			return nil, nil, fmt.Errorf("column permutation (%v) must be a length ncol %v", len(opts.colPerm), ncol)
 		}
 		for _, v := range opts.colPerm {
 			if v < 0 || v >= ncol {
-				return nil, fmt.Errorf("column permutation %v out of range [0,%d)", v, ncol)
+				return nil, nil, fmt.Errorf("column permutation %v out of range [0,%d)", v, ncol)
 			}
 		}
 	}
@@ -217,6 +507,11 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 	//}
 
 	// Allocate work arrays.
+	var drop *dropRule
+	if opts.drop != nil {
+		rule := *opts.drop
+		drop = &rule
+	}
 	rwork := make([]float64, nrow)
 	twork := make([]float64, nrow)
 	found := make([]int, nrow)
@@ -224,8 +519,18 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 	parent := make([]int, nrow)
 	pattern := make([]int, nrow)
 
-	// Create lu structure.
+	// Create lu structure. With an analysis, there is room for the
+	// fill of the last column before it is copied into place.
 	luSize := int(float64(nnzA) * opts.fillRatio)
+	if an := opts.analysis; an != nil {
+		if err := an.check(nA, rowind, colptr, opts); err != nil {
+			return nil, nil, err
+		}
+		luSize = an.nnzLU + nrow + 1
+	}
+	if opts.maxLUNZ > 0 && luSize > opts.maxLUNZ {
+		luSize = opts.maxLUNZ
+	}
 	lu := &LU{
 		luSize:   luSize,
 		luNZ:     make([]float64, luSize),
@@ -235,15 +540,20 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 		rowPerm:  make([]int, nrow),
 		colPerm:  make([]int, ncol),
 		nA:       nA,
+		nCol:     k,
+		rank:     k,
+		maxSize:  opts.maxLUNZ,
+		opts:     opts,
 	}
 
 	// Compute max matching. We use elements of the lu structure
-	// for all the temporary arrays needed.
+	// for the temporary arrays needed, except for nxtchp, since the
+	// storage for L and U may be limited to fewer than ncol elements.
 
 	rmatch, cmatch, err := maxmatch(nrow, ncol, colptrA, rowindA,
-		lu.lColPtr, lu.uColPtr, lu.rowPerm, lu.colPerm, lu.luRowInd)
+		lu.lColPtr, lu.uColPtr, lu.rowPerm, lu.colPerm, make([]int, ncol))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for jcol := 0; jcol < ncol; jcol++ {
@@ -285,27 +595,36 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 		}
 	}
 
-	// Compute one column at a time.
-	for jcol := 1; jcol <= ncol; jcol++ {
+	serial := opts.workers == 1 || partial
+	switchDense := serial && opts.denseThreshold > 0 && !partial && !opts.rankDeficient && drop == nil
+	nsparse := lu.rank
+	var sn *supernodes
+	if serial && opts.supernodal && drop == nil {
+		sn = newSupernodes(nrow, ncol)
+	}
+	if !serial {
+		if opts.rankDeficient {
+			return nil, nil, errors.New("parallel factorization may not be rank-deficient")
+		}
+		lastlu, err = lu.factorParallel(opts, drop, nzA, rowindA, colptrA, rmatch, cmatch)
+		if err == errFillBound {
+			// The actual fill may still be within the limit, so the
+			// columns are computed serially instead.
+			serial = true
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Compute one column at a time. In rank-deficient mode, columns
+	// without an acceptable pivot are moved to the end of the column
+	// permutation and the remaining columns are shifted down.
+	for jcol := 1; serial && jcol <= lu.rank; jcol++ {
 		// Mark pointer to new column, ensure it is large enough.
 		if lastlu+nrow >= lu.luSize {
-			newSize := int(float64(lu.luSize) * opts.expandRatio)
-
-			if Logger != nil {
-				fmt.Fprintf(Logger, "expanding LU to %d nonzeros\n", newSize)
+			if err := lu.expand(opts.expandRatio, lastlu, jcol); err != nil {
+				return nil, nil, err
 			}
-
-			luNZ := make([]float64, newSize)
-			copy(luNZ, lu.luNZ)
-			lu.luNZ = luNZ
-			//lu.luNZ = append(lu.luNZ, make([]float64, newSize-lu.luSize)...)
-
-			luRowInd := make([]int, newSize)
-			copy(luRowInd, lu.luRowInd)
-			lu.luRowInd = luRowInd
-			//lu.luRowInd = append(lu.luRowInd, make([]int, newSize-lu.luSize)...)
-
-			lu.luSize = newSize
 		}
 
 		// Set up nonzero pattern.
@@ -319,10 +638,13 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 			thisCol = lu.colPerm[jcol-1]
 			origRow = cmatch[thisCol-1]
 
-			pattern[origRow-1] = 2
+			// A structurally singular column may have no matched row.
+			if origRow != 0 {
+				pattern[origRow-1] = 2
 
-			if lu.rowPerm[origRow-1] != 0 {
-				return nil, fmt.Errorf("pivot row from max-matching already used")
+				if lu.rowPerm[origRow-1] != 0 {
+					return nil, nil, fmt.Errorf("pivot row from max-matching already used")
+				}
 			}
 			// pattern[ thisCol - 1 ] = 2
 		}
@@ -335,14 +657,42 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 			lu.luRowInd, lu.lColPtr, lu.uColPtr,
 			lu.rowPerm, lu.colPerm, rwork, found, parent, child)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// Compute the values of column jcol of L and U in the dense
 		// vector, allocating storage for fill in L as necessary.
 
+		if drop != nil {
+			drop.colNorm = norm2(nzA[colptrA[thisCol-1]-1 : colptrA[thisCol]-1])
+		}
 		lucomp(jcol, &lastlu, lu.luNZ, lu.luRowInd, lu.lColPtr, lu.uColPtr,
-			lu.rowPerm, lu.colPerm, rwork, found, pattern)
+			lu.rowPerm, lu.colPerm, rwork, found, pattern, drop, sn)
+
+		if opts.rankDeficient && !hasPivot(opts.rankTol, jcol, lastlu, nzA, rowindA, colptrA,
+			lu.luRowInd, lu.lColPtr, lu.uColPtr, lu.colPerm, rwork) {
+			if Logger != nil {
+				fmt.Fprintf(Logger, "deferring column %d\n", thisCol-1)
+			}
+			for i := lu.uColPtr[jcol-1] - 1; i < lastlu; i++ {
+				irow := lu.luRowInd[i] - 1
+				rwork[irow] = 0
+				found[irow] = 0
+			}
+			for i := colptrA[thisCol-1]; i < colptrA[thisCol]; i++ {
+				pattern[rowindA[i-1]-1] = 0
+			}
+			if origRow != 0 {
+				pattern[origRow-1] = 0
+			}
+			lastlu = lu.uColPtr[jcol-1] - 1
+
+			copy(lu.colPerm[jcol-1:], lu.colPerm[jcol:])
+			lu.colPerm[ncol-1] = thisCol
+			lu.rank--
+			jcol--
+			continue
+		}
 
 		//if rwork[origRow-1] == 0.0 {
 		//	fmt.Printf("Warning: Matching to a zero\n")
@@ -358,14 +708,19 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 		// column of L by it.
 		nzCountLimit := int(opts.colFillRatio * (float64(colptrA[thisCol] - colptrA[thisCol-1] + 1)))
 
+		var dropped float64
 		zpivot, err := lucopy(localPivotPolicy, opts.pivotThreshold, opts.dropThreshold,
 			nzCountLimit, jcol, ncol, &lastlu, lu.luNZ, lu.luRowInd, lu.lColPtr, lu.uColPtr,
-			lu.rowPerm, lu.colPerm, rwork, pattern, twork)
+			lu.rowPerm, lu.colPerm, rwork, pattern, twork, drop, opts.modified, &dropped)
 		if err != nil {
-			return nil, err
+			return nil, nil, &pivotError{err}
 		}
+		lu.dropped += dropped
 		if zpivot == -1 {
-			return nil, fmt.Errorf("lucopy: jcol=%v", jcol)
+			return nil, nil, &pivotError{fmt.Errorf("lucopy: jcol=%v", jcol)}
+		}
+		if sn != nil {
+			sn.add(jcol, zpivot, lu.luRowInd, lu.luNZ, lu.lColPtr, lu.uColPtr)
 		}
 
 		{
@@ -374,14 +729,17 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 				pattern[rowindA[i-1]-1] = 0
 			}
 
-			pattern[origRow-1] = 0
-
 			pivtRow := zpivot
 			othrCol := rmatch[pivtRow-1]
 
 			cmatch[thisCol-1] = pivtRow
-			cmatch[othrCol-1] = origRow
-			rmatch[origRow-1] = othrCol
+			if othrCol != 0 {
+				cmatch[othrCol-1] = origRow
+			}
+			if origRow != 0 {
+				pattern[origRow-1] = 0
+				rmatch[origRow-1] = othrCol
+			}
 			rmatch[pivtRow-1] = thisCol
 
 			//pattern[thisCol - 1] = 0
@@ -391,11 +749,40 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 		if jcol == nrow {
 			localPivotPolicy = noDiagonalElement
 		}
+
+		// Switch to dense factorization if the trailing submatrix has
+		// filled in.
+		if switchDense && lu.trailingIsDense(jcol, opts.denseThreshold) {
+			nsparse = jcol
+			break
+		}
+	}
+
+	// Compute the Schur complement of the remaining columns or the
+	// columns of U for the deferred columns.
+	var schur *Schur
+	if partial {
+		lu.nCol = lu.rank
+		schur, err = luschur(lu, nzA, rowindA, colptrA, &lastlu, opts.expandRatio, rwork, found, parent, child)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if nsparse < lu.rank {
+		err := lu.factorTrailing(nsparse, nzA, rowindA, colptrA, &lastlu, opts.expandRatio, rwork, found, parent, child)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if lu.rank < ncol {
+		lu.nCol = ncol
+		err := ludefer(lu, nzA, rowindA, colptrA, &lastlu, opts.expandRatio, rwork, found, parent, child)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Fill in the zero entries of the permutation vector, and renumber the
 	// rows so the data structure represents L and U, not PtL and PtU.
-	jcol := ncol + 1
+	jcol := lu.rank + 1
 	for i := 0; i < nrow; i++ {
 		if lu.rowPerm[i] == 0 {
 			lu.rowPerm[i] = jcol
@@ -406,6 +793,7 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 	for i := 0; i < lastlu; i++ {
 		lu.luRowInd[i] = lu.rowPerm[lu.luRowInd[i]-1]
 	}
+	lu.trim(lastlu)
 
 	//fmt.Printf("rperm:\n[")
 	//for i := 0; i < ncol; i++ {
@@ -423,8 +811,8 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 		var ujj float64
 		var minujj = math.Inf(1)
 
-		for jcol := 1; jcol <= ncol; jcol++ {
-			ujj = math.Abs(lu.luNZ[lu.lColPtr[jcol-1]-2])
+		for jcol := 1; jcol <= lu.nCol; jcol++ {
+			ujj = math.Abs(lu.pivot(jcol))
 			if ujj < minujj {
 				minujj = ujj
 			}
@@ -433,16 +821,20 @@ func Factor(nA int, rowind, colptr []int, nzA []float64, optFuncs ...OptFunc) (*
 		fmt.Fprintf(Logger, "last = %v, min = %v\n", ujj, minujj)
 	}
 
-	return lu, nil
+	return lu, schur, nil
 }
 
 // Solve Ax=b for one or more right-hand-sides given the numeric
-// factorization of A from Factor.
+// factorization of A from Factor. If A is rank-deficient a basic
+// solution is computed, see RankDeficient.
 func Solve(lu *LU, rhs [][]float64, trans bool) error {
 	if lu == nil {
 		return errors.New("lu must not be nil")
 	}
 	n := lu.nA
+	if lu.nCol != n {
+		return fmt.Errorf("factorization is incomplete (%v of %v columns)", lu.nCol, n)
+	}
 	if len(rhs) == 0 {
 		return fmt.Errorf("one or more rhs must be specified")
 	}
@@ -454,21 +846,43 @@ func Solve(lu *LU, rhs [][]float64, trans bool) error {
 	work := make([]float64, n)
 
 	for _, b := range rhs {
+		if lu.rank < n {
+			if err := solveBasic(lu, b, work, trans); err != nil {
+				return err
+			}
+			continue
+		}
+		if lu.upd != nil {
+			if err := solveUpdated(lu, b, work, trans); err != nil {
+				return err
+			}
+			continue
+		}
+		if lu.dense != nil {
+			if err := lu.dense.solve(lu, b, work, trans); err != nil {
+				return err
+			}
+			continue
+		}
+		if lu.sched != nil {
+			lu.sched.solve(lu, b, work, trans)
+			continue
+		}
 		if !trans {
-			err := lsolve(n, lu.luNZ, lu.luRowInd, lu.lColPtr, lu.uColPtr, lu.rowPerm, lu.colPerm, b, work)
+			err := lu.lsolve(b, work)
 			if err != nil {
 				return fmt.Errorf("lsolve: %v", err)
 			}
-			err = usolve(n, lu.luNZ, lu.luRowInd, lu.lColPtr, lu.uColPtr, lu.rowPerm, lu.colPerm, work, b)
+			err = lu.usolve(work, b)
 			if err != nil {
 				return fmt.Errorf("usolve: %v", err)
 			}
 		} else {
-			err := utsolve(n, lu.luNZ, lu.luRowInd, lu.lColPtr, lu.uColPtr, lu.rowPerm, lu.colPerm, b, work)
+			err := lu.utsolve(b, work)
 			if err != nil {
 				return fmt.Errorf("utsolve: %v", err)
 			}
-			err = ltsolve(n, lu.luNZ, lu.luRowInd, lu.lColPtr, lu.uColPtr, lu.rowPerm, lu.colPerm, work, b)
+			err = lu.ltsolve(work, b)
 			if err != nil {
 				return fmt.Errorf("ltsolve: %v", err)
 			}
@@ -476,3 +890,14 @@ func Solve(lu *LU, rhs [][]float64, trans bool) error {
 	}
 	return nil
 }
+
+// Solve sets dst to the solution of Ax = src given the numeric
+// factorization of A from Factor, so that LU may be used as a
+// preconditioner. The dst and src slices may be the same.
+func (lu *LU) Solve(dst, src []float64) error {
+	if len(dst) != len(src) {
+		return fmt.Errorf("len dst (%v) must equal len src (%v)", len(dst), len(src))
+	}
+	copy(dst, src)
+	return Solve(lu, [][]float64{dst}, false)
+}
@@ -0,0 +1,98 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"math"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestFactors(t *testing.T) {
+	n, rowind, colst, nzA := grid(12, 12)
+	nH, rowindH, colstH, nzH := lhr01()
+	rowindR, colptrR, nzR := csc([][]float64{
+		{4, 1, 6, 0, 4},
+		{1, 3, 7, 1, 2},
+		{0, 1, 2, 0, 0},
+		{2, 0, 2, 5, 7},
+		{0, 2, 4, 1, 1},
+	})
+
+	for _, test := range []struct {
+		name   string
+		n      int
+		rowind []int
+		colptr []int
+		nz     []float64
+		opts   []gp.OptFunc
+	}{
+		{"compact", n, rowind, colst, nzA, nil},
+		{"wide", n, rowind, colst, nzA, []gp.OptFunc{gp.WideIndexes()}},
+		{"threshold", n, rowind, colst, nzA, []gp.OptFunc{gp.PartialPivoting(0.1)}},
+		{"dense", n, rowind, colst, nzA, []gp.OptFunc{gp.DenseThreshold(0.05)}},
+		{"dense unsymmetric", nH, rowindH, colstH, nzH, []gp.OptFunc{gp.DenseThreshold(0.05)}},
+		{"rank", 5, rowindR, colptrR, nzR, []gp.OptFunc{gp.RankDeficient(1e-12)}},
+	} {
+		lu, err := gp.Factor(test.n, test.rowind, test.colptr, test.nz, test.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		f, err := lu.Factors()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		m := test.n
+		if f.N != m || len(f.LColPtr) != m+1 || len(f.UColPtr) != m+1 {
+			t.Fatalf("%s: invalid dimensions", test.name)
+		}
+
+		// Form PAQ and LU densely.
+		paq := make([]float64, m*m)
+		for j := 0; j < m; j++ {
+			col := f.ColPerm[j]
+			for p := test.colptr[col]; p < test.colptr[col+1]; p++ {
+				paq[f.RowPerm[test.rowind[p]]*m+j] += test.nz[p]
+			}
+		}
+		prod := make([]float64, m*m)
+		for j := 0; j < m; j++ {
+			last := -1
+			for p := f.UColPtr[j]; p < f.UColPtr[j+1]; p++ {
+				k := f.URowInd[p]
+				if k > j || k <= last {
+					t.Fatalf("%s: U(%d,%d) out of order", test.name, k, j)
+				}
+				last = k
+				for q := f.LColPtr[k]; q < f.LColPtr[k+1]; q++ {
+					prod[f.LRowInd[q]*m+j] += f.LNZ[q] * f.UNZ[p]
+				}
+			}
+		}
+		for k := 0; k < m; k++ {
+			p := f.LColPtr[k]
+			if f.LRowInd[p] != k || f.LNZ[p] != 1 {
+				t.Fatalf("%s: L(%d,%d) is not one", test.name, k, k)
+			}
+			for q := p + 1; q < f.LColPtr[k+1]; q++ {
+				if f.LRowInd[q] <= f.LRowInd[q-1] {
+					t.Fatalf("%s: L(:,%d) out of order", test.name, k)
+				}
+			}
+		}
+		var diff, norm float64
+		for i := range paq {
+			diff = math.Hypot(diff, paq[i]-prod[i])
+			norm = math.Hypot(norm, paq[i])
+		}
+		if diff/norm > 1e-14 {
+			t.Errorf("%s: ||PAQ - LU|| / ||A|| = %v", test.name, diff/norm)
+		}
+	}
+
+	if _, err := new(gp.LU).Factors(); err == nil {
+		t.Errorf("expected error for empty factorization")
+	}
+}
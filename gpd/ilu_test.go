@@ -5,6 +5,7 @@ package gpd_test
 
 import (
 	"math"
+	"math/rand"
 	"os"
 	"testing"
 
@@ -120,3 +121,232 @@ func residual(x []float64) float64 {
 	}
 	return norm
 }
+
+func TestILUT(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+
+	x0 := make([]float64, n)
+	for i := range x0 {
+		x0[i] = 1
+	}
+	b := matVec(n, rowind, colst, nzA, x0)
+
+	complete, err := gp.NewILUT(n, rowind, colst, nzA, 0, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if complete.DroppedNorm() != 0 {
+		t.Errorf("dropped norm, expected 0 actual %v", complete.DroppedNorm())
+	}
+	x := make([]float64, n)
+	if err := complete.Apply(x, b); err != nil {
+		t.Fatal(err)
+	}
+	if resid := residual(x); resid > 1e-10 {
+		t.Errorf("resid, expected < %v actual %v", 1e-10, resid)
+	}
+
+	ilu, err := gp.NewILUT(n, rowind, colst, nzA, 1e-4, 50, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ilu.NNZ() >= complete.NNZ() {
+		t.Errorf("nnz, expected < %v actual %v", complete.NNZ(), ilu.NNZ())
+	}
+	if ilu.DroppedNorm() == 0 {
+		t.Errorf("dropped norm, expected > 0")
+	}
+	t.Logf("fill %v (complete %v), dropped norm %v", ilu.Fill(), complete.Fill(), ilu.DroppedNorm())
+
+	if err := ilu.ApplyTrans(x, b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestILUK(t *testing.T) {
+	// 2D Laplacian on a 6x6 grid.
+	const m = 6
+	n := m * m
+	dense := laplacian(m)
+	rowind, colptr, nzA := csc(dense)
+
+	x0 := make([]float64, n)
+	for i := range x0 {
+		x0[i] = 1
+	}
+	b := matVec(n, rowind, colptr, nzA, x0)
+
+	var lastNNZ int
+	for level := 0; level <= n; level++ {
+		p, err := gp.AnalyzeILUK(n, rowind, colptr, level)
+		if err != nil {
+			t.Fatalf("level %d: %v", level, err)
+		}
+		if level == 0 && p.NNZ() != len(nzA) {
+			t.Errorf("ILU(0) nnz, expected %v actual %v", len(nzA), p.NNZ())
+		}
+		if p.NNZ() < lastNNZ {
+			t.Errorf("level %d: nnz %v less than %v", level, p.NNZ(), lastNNZ)
+		}
+		lastNNZ = p.NNZ()
+
+		// The symbolic factorization is reused for scaled values.
+		for _, scale := range []float64{1, 2} {
+			nz := make([]float64, len(nzA))
+			for i := range nz {
+				nz[i] = scale * nzA[i]
+			}
+			ilu, err := p.Factor(nz)
+			if err != nil {
+				t.Fatalf("level %d: %v", level, err)
+			}
+			if ilu.NNZ() != p.NNZ() {
+				t.Errorf("level %d: nnz, expected %v actual %v", level, p.NNZ(), ilu.NNZ())
+			}
+
+			x := make([]float64, n)
+			for i := range x {
+				x[i] = scale * b[i]
+			}
+			if err := ilu.Apply(x, x); err != nil {
+				t.Fatal(err)
+			}
+			resid := residual(x)
+			if level >= m && (resid > 1e-12 || ilu.DroppedNorm() != 0) {
+				t.Errorf("level %d: resid %v, dropped norm %v", level, resid, ilu.DroppedNorm())
+			}
+			if level == 0 && ilu.DroppedNorm() == 0 {
+				t.Errorf("ILU(0) dropped norm, expected > 0")
+			}
+		}
+	}
+}
+
+func TestMILUT(t *testing.T) {
+	const m = 10
+	n := m * m
+	rowind, colst, nzA := csc(laplacian(m))
+
+	// Row sums are preserved, so the factorization is exact for a
+	// vector of ones.
+	x0 := make([]float64, n)
+	for i := range x0 {
+		x0[i] = 1
+	}
+	b := matVec(n, rowind, colst, nzA, x0)
+	for _, dropTol := range []float64{1e-1, 1e-2} {
+		ilu, err := gp.NewMILUT(n, rowind, colst, nzA, dropTol, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ilu.DroppedNorm() == 0 {
+			t.Errorf("drop %v: dropped norm, expected > 0", dropTol)
+		}
+
+		x := make([]float64, n)
+		if err := ilu.Apply(x, b); err != nil {
+			t.Fatal(err)
+		}
+		if resid := residual(x); resid > 1e-12 {
+			t.Errorf("drop %v: resid %v", dropTol, resid)
+		}
+	}
+
+	// A random nonsymmetric matrix, for which ILUT is inexact.
+	rnd := rand.New(rand.NewSource(1))
+	dense := make([][]float64, n)
+	for i := range dense {
+		dense[i] = make([]float64, n)
+		dense[i][i] = 4
+		for k := 0; k < 4; k++ {
+			dense[i][rnd.Intn(n)] += rnd.Float64()*2 - 1
+		}
+	}
+	rowind, colst, nzA = csc(dense)
+	b = matVec(n, rowind, colst, nzA, x0)
+	for _, test := range []struct {
+		name     string
+		factor   func(int, []int, []int, []float64, float64, int, float64, ...gp.OptFunc) (*gp.ILU, error)
+		min, max float64
+	}{
+		{"ILUT", gp.NewILUT, 1e-3, math.Inf(1)},
+		{"MILUT", gp.NewMILUT, 0, 1e-12},
+	} {
+		ilu, err := test.factor(n, rowind, colst, nzA, 0.1, 0, 1)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		x := make([]float64, n)
+		if err := ilu.Apply(x, b); err != nil {
+			t.Fatal(err)
+		}
+		resid := residual(x)
+		if resid < test.min || resid > test.max {
+			t.Errorf("%s: resid %v out of range [%v,%v]", test.name, resid, test.min, test.max)
+		}
+	}
+}
+
+func TestDiagonalShift(t *testing.T) {
+	// The second pivot is zero without row interchanges.
+	n := 3
+	rowind, colst, nzA := csc([][]float64{
+		{1, 1, 0},
+		{1, 1, 1},
+		{0, 1, 2},
+	})
+
+	_, err := gp.Factor(n, rowind, colst, nzA, gp.WithoutPivoting())
+	if err == nil {
+		t.Fatal("expected zero pivot")
+	}
+
+	lu, err := gp.Factor(n, rowind, colst, nzA, gp.WithoutPivoting(), gp.DiagonalShift(1e-2, 1e-8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lu.Shift() != 1e-2 {
+		t.Errorf("shift, expected %v actual %v", 1e-2, lu.Shift())
+	}
+
+	// The shift doubles until the pivots exceed the tolerance.
+	lu, err = gp.Factor(n, rowind, colst, nzA, gp.WithoutPivoting(), gp.DiagonalShift(1e-2, 0.1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lu.Shift() <= 1e-2 {
+		t.Errorf("shift, expected > %v actual %v", 1e-2, lu.Shift())
+	}
+
+	// No shift is used if the factorization succeeds.
+	lu, err = gp.Factor(n, rowind, colst, nzA, gp.DiagonalShift(1e-2, 1e-8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lu.Shift() != 0 {
+		t.Errorf("shift, expected 0 actual %v", lu.Shift())
+	}
+}
+
+// laplacian returns the 5-point Laplacian on an m by m grid.
+func laplacian(m int) [][]float64 {
+	n := m * m
+	dense := make([][]float64, n)
+	for i := range dense {
+		dense[i] = make([]float64, n)
+		dense[i][i] = 4
+		if i%m != 0 {
+			dense[i][i-1] = -1
+		}
+		if i%m != m-1 {
+			dense[i][i+1] = -1
+		}
+		if i >= m {
+			dense[i][i-m] = -1
+		}
+		if i < n-m {
+			dense[i][i+m] = -1
+		}
+	}
+	return dense
+}
@@ -0,0 +1,80 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"math"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestAnalyzeSolve(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+
+	// Many independent blocks give level sets wide enough to be
+	// solved concurrently.
+	const nblock, bs = 600, 4
+	blocks := make([][]float64, nblock*bs)
+	for i := range blocks {
+		blocks[i] = make([]float64, nblock*bs)
+	}
+	for k := 0; k < nblock; k++ {
+		for i := 0; i < bs; i++ {
+			for j := 0; j < bs; j++ {
+				blocks[k*bs+i][k*bs+j] = math.Cos(float64(k + 3*i + 7*j))
+			}
+			blocks[k*bs+i][k*bs+i] += 4
+		}
+	}
+	brow, bcol, bnz := csc(blocks)
+
+	for _, test := range []struct {
+		name   string
+		n      int
+		rowind []int
+		colst  []int
+		nzA    []float64
+	}{
+		{"lhr01", n, rowind, colst, nzA},
+		{"blocks", nblock * bs, brow, bcol, bnz},
+	} {
+		lu, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		b := make([]float64, test.n)
+		for i := range b {
+			b[i] = math.Sin(float64(i))
+		}
+
+		var want [2][]float64
+		for k, trans := range []bool{false, true} {
+			want[k] = make([]float64, test.n)
+			copy(want[k], b)
+			if err := gp.Solve(lu, [][]float64{want[k]}, trans); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, workers := range []int{1, 4} {
+			if err := lu.AnalyzeSolve(workers); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			for k, trans := range []bool{false, true} {
+				x := make([]float64, test.n)
+				copy(x, b)
+				if err := gp.Solve(lu, [][]float64{x}, trans); err != nil {
+					t.Fatal(err)
+				}
+				for i := range x {
+					if x[i] != want[k][i] {
+						t.Fatalf("%s: workers=%d trans=%v: x[%d] expected %v actual %v",
+							test.name, workers, trans, i, want[k][i], x[i])
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestMaxLUNonzeros(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+
+	lu, err := gp.Factor(n, rowind, colst, nzA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nnz := lu.NNZ()
+
+	// Each entry is a value and its row index.
+	entryBytes := int64(8 + strconv.IntSize/8)
+	for _, opt := range []gp.OptFunc{
+		gp.MaxLUNonzeros(nnz / 2),
+		gp.MaxMemory(int64(nnz/2) * entryBytes),
+	} {
+		_, err := gp.Factor(n, rowind, colst, nzA, opt)
+		e, ok := err.(*gp.MemoryLimitError)
+		if !ok {
+			t.Fatalf("expected *MemoryLimitError, got %v", err)
+		}
+		if e.Limit != nnz/2 {
+			t.Errorf("limit expected %v actual %v", nnz/2, e.Limit)
+		}
+		if e.Column <= 0 || e.Column >= n {
+			t.Errorf("column %v out of range", e.Column)
+		}
+		if e.NNZ > e.Limit {
+			t.Errorf("nnz %v exceeds limit %v", e.NNZ, e.Limit)
+		}
+	}
+
+	// The storage is trimmed to the nonzeros, and the extra storage
+	// for the fill of a column is within the limit.
+	lu, err = gp.Factor(n, rowind, colst, nzA, gp.MaxLUNonzeros(nnz+n+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]float64, n)
+	for i := range b {
+		b[i] = 1
+	}
+	b = matVec(n, rowind, colst, nzA, b)
+	if err := gp.Solve(lu, [][]float64{b}, false); err != nil {
+		t.Fatal(err)
+	}
+	for i := range b {
+		if math.Abs(b[i]-1) > 1e-8 {
+			t.Fatalf("x[%d] expected 1 actual %v", i, b[i])
+		}
+	}
+
+	// A diagonal shift does not recover from the storage limit.
+	var log bytes.Buffer
+	logger := gp.Logger
+	gp.Logger = &log
+	_, err = gp.Factor(n, rowind, colst, nzA, gp.MaxLUNonzeros(nnz/2), gp.DiagonalShift(1e-2, 0))
+	gp.Logger = logger
+	var e *gp.MemoryLimitError
+	if !errors.As(err, &e) {
+		t.Errorf("expected *MemoryLimitError with diagonal shift, got %v", err)
+	}
+	if strings.Contains(log.String(), "retrying") {
+		t.Errorf("storage limit retried with diagonal shift")
+	}
+
+	if _, err := gp.Factor(n, rowind, colst, nzA, gp.MaxLUNonzeros(0)); err == nil {
+		t.Error("expected error for zero limit")
+	}
+	_, err = gp.Factor(n, rowind, colst, nzA, gp.MaxMemory(entryBytes-1))
+	if err == nil || !strings.Contains(err.Error(), "bytes") {
+		t.Errorf("expected error in bytes for memory limit, got %v", err)
+	}
+}
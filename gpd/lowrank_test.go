@@ -0,0 +1,82 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"math"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestLowRankUpdate(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+
+	lu, err := gp.Factor(n, rowind, colst, nzA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := [][]float64{make([]float64, n), make([]float64, n)}
+	v := [][]float64{make([]float64, n), make([]float64, n)}
+	for i := 0; i < n; i++ {
+		u[0][i] = math.Sin(float64(i))
+		u[1][i] = math.Cos(float64(2 * i))
+		v[0][i] = math.Cos(float64(3*i)) / float64(n)
+		v[1][i] = math.Sin(float64(5*i)) / float64(n)
+	}
+
+	// The columns are copied, so changing them afterwards has no
+	// effect on the solutions.
+	uc := [][]float64{append([]float64(nil), u[0]...), append([]float64(nil), u[1]...)}
+	vc := [][]float64{append([]float64(nil), v[0]...), append([]float64(nil), v[1]...)}
+	lr, err := gp.NewLowRankUpdate(lu, uc, vc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range uc {
+		for i := range uc[k] {
+			uc[k][i], vc[k][i] = 0, 0
+		}
+	}
+
+	x0 := make([]float64, n)
+	for i := range x0 {
+		x0[i] = 1
+	}
+
+	for _, trans := range []bool{false, true} {
+		// b = (A + UV')x0 or (A' + VU')x0.
+		var b []float64
+		if !trans {
+			b = matVec(n, rowind, colst, nzA, x0)
+		} else {
+			b = matTransVec(n, rowind, colst, nzA, x0)
+		}
+		l, r := u, v
+		if trans {
+			l, r = v, u
+		}
+		for k := range l {
+			var s float64
+			for i := range x0 {
+				s += r[k][i] * x0[i]
+			}
+			for i := range b {
+				b[i] += l[k][i] * s
+			}
+		}
+
+		if err := lr.Solve([][]float64{b}, trans); err != nil {
+			t.Fatal(err)
+		}
+
+		const eps = 1e-10
+
+		resid := residual(b)
+		if resid > eps {
+			t.Errorf("resid (trans=%v), expected < %v actual %v", trans, eps, resid)
+		}
+	}
+}
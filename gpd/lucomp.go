@@ -36,10 +36,13 @@ package gpd
 //   found   found(i)=jcol if storage for position (i,jcol) has been
 //           allocated in the sparse data structure.
 //   flops   flop count
+//   drop    if not nil, elements of U below the drop tolerance are not
+//           used to update the column, since they will be dropped.
+//   sn      if not nil, supernodes of L used for dense updates.
 //
 //           Both dense and found are indexed according to the row
 //           numbering of A, not PA.
-func lucomp(jcol int, lastlu *int, lu []float64, lurow, lcolst, ucolst, rperm, cperm []int, dense []float64, found, pattern []int) {
+func lucomp(jcol int, lastlu *int, lu []float64, lurow, lcolst, ucolst, rperm, cperm []int, dense []float64, found, pattern []int, drop *dropRule, sn *supernodes) {
 	// Local variables:
 	//   nzuptr                pointer to current nonzero PtU(krow,jcol).
 	//   nzuend, nnzu, nzuind  used to compute nzuptr.
@@ -55,6 +58,9 @@ func lucomp(jcol int, lastlu *int, lu []float64, lurow, lcolst, ucolst, rperm, c
 
 	//    For each krow with PtU(krow,jcol) != 0, in reverse postorder, use
 	//    column kcol = rperm(krow) of L to update the current column.
+	if sn != nil {
+		sn.pass++
+	}
 	nzuend := lcolst[jcol-off]
 	nnzu := nzuend - ucolst[jcol-off]
 	if nnzu != 0 {
@@ -66,6 +72,25 @@ func lucomp(jcol int, lastlu *int, lu []float64, lurow, lcolst, ucolst, rperm, c
 			//if pattern[rperm[krow]-off] == 0 {
 			//	ukj = 0
 			//}
+			if drop != nil && abs(ukj) < drop.tol*drop.colNorm {
+				continue
+			}
+
+			// The members of a supernode are reached in order and, if
+			// there are enough of them, are updated together at the last.
+			if sn != nil && sn.of[kcol] != -1 {
+				s := sn.list[sn.of[kcol]]
+				if s.stamp != sn.pass {
+					s.stamp = sn.pass
+					s.start = kcol + 1
+				}
+				if s.last-s.start+1 >= minSupernode && len(s.rows) >= minSupernodeRows {
+					if kcol+1 == s.last {
+						sn.update(s, jcol, lastlu, lu, lurow, lcolst, ucolst, dense, found)
+					}
+					continue
+				}
+			}
 
 			// For each irow with PtL(irow,kcol) != 0, update PtL(irow,jcol) or PtU(irow,jcol)
 
@@ -45,12 +45,18 @@ import (
 //   dense                  On entry, column jcol of Pt(U(jcol,jcol)*(L-I)+U).
 //                          On exit, zero.
 //   flops                  flop count
+//   dropped                Sum of squares of the magnitudes of the
+//                          dropped elements, updated here.
 //
 // Output variable:
 //   zpivot                 > 0 for success (pivot row), -1 for zero pivot element.
+//
+// If drop is not nil, its rule replaces dthresh and nzcount and the
+// elements in the pattern of A may also be dropped.
 func lucopy(pivot pivotPolicy, pthresh, dthresh float64, nzcount int,
 	jcol1, ncol int, lastlu *int, lu []float64, lurow, lcolst, ucolst []int,
-	rperm, cperm []int, dense []float64, pattern []int, twork []float64) (int, error) {
+	rperm, cperm []int, dense []float64, pattern []int, twork []float64,
+	drop *dropRule, modified bool, dropped *float64) (int, error) {
 	jcol := jcol1 - 1 // zero based column
 	// Local variables:
 	//   nzptr       Index into lurow of current nonzero.
@@ -64,14 +70,17 @@ func lucopy(pivot pivotPolicy, pthresh, dthresh float64, nzcount int,
 	//   dptr        Temporary index into lu and lurow.
 	//   diagptr     Index to diagonal element of QAQt
 	//   diagpiv     Value of diagonal element
+	//   comp        Sum of dropped elements, for modified ILU
 
 	// Copy column jcol from dense to sparse, recording the position of
 	// the diagonal element.
 	var ujjptr int
+	var comp float64
 
 	if pivot == noPivoting || pivot == noDiagonalElement {
 		// No pivoting, diagonal element has irow = jcol.
-		// Copy the column elements of U and L, throwing out zeros.
+		// Copy the column elements of U and L. Incomplete factorization
+		// by level of fill is provided by ILUK.
 
 		if ucolst[jcol+1]-1 < ucolst[jcol] {
 			//zpivot = -1
@@ -83,14 +92,10 @@ func lucopy(pivot pivotPolicy, pthresh, dthresh float64, nzcount int,
 		for nzptr := ucolst[jcol] - 1; nzptr < lcolst[jcol]-1; nzptr++ {
 			irow := lurow[nzptr] - 1
 
-			if pattern[irow] != 0 || irow == cperm[jcol]-1 {
-				lurow[nzcpy] = irow + 1
-				lu[nzcpy] = dense[irow]
-				dense[irow] = 0
-				nzcpy++
-			} else {
-				dense[irow] = 0
-			}
+			lurow[nzcpy] = irow + 1
+			lu[nzcpy] = dense[irow]
+			dense[irow] = 0
+			nzcpy++
 		}
 		lastu := nzcpy
 
@@ -101,14 +106,10 @@ func lucopy(pivot pivotPolicy, pthresh, dthresh float64, nzcount int,
 			if pattern[irow] == 2 {
 				ujjptr = nzcpy + 1
 			}
-			if pattern[irow] != 0 || /*irow == cperm(jcol-off)) then*/ pattern[irow] == 2 {
-				lurow[nzcpy] = irow + 1
-				lu[nzcpy] = dense[irow]
-				dense[irow] = 0
-				nzcpy++
-			} else {
-				dense[irow] = 0
-			}
+			lurow[nzcpy] = irow + 1
+			lu[nzcpy] = dense[irow]
+			dense[irow] = 0
+			nzcpy++
 		}
 
 		lcolst[jcol] = lastu + 1
@@ -131,7 +132,10 @@ func lucopy(pivot pivotPolicy, pthresh, dthresh float64, nzcount int,
 
 		// Partial pivoting, diagonal elt. has max. magnitude in L.
 		// Compute the drop threshold for the column
-		if nzcount <= 0 {
+		if drop != nil {
+			udthreshabs = drop.threshold(lurow, ucolst[jcol]-1, lcolst[jcol]-1, dense, twork)
+			ldthreshabs = drop.threshold(lurow, lcolst[jcol]-1, ucolst[jcol+1]-1, dense, twork)
+		} else if nzcount <= 0 {
 			maxpivglb := -1.0
 			for nzptr := ucolst[jcol] - 1; nzptr < lcolst[jcol]-1; nzptr++ {
 				irow := lurow[nzptr]
@@ -188,12 +192,14 @@ func lucopy(pivot pivotPolicy, pthresh, dthresh float64, nzcount int,
 				irow := lurow[nzptr] - 1
 
 				//if (pattern(irow) .ne. 0 .or. pattern(irow) .eq. 2) then
-				if pattern[irow] != 0 || abs(dense[irow]) >= udthreshabs {
+				if (pattern[irow] != 0 && drop == nil) || abs(dense[irow]) >= udthreshabs {
 					lurow[nzcpy] = irow + 1
 					lu[nzcpy] = dense[irow]
 					dense[irow] = 0
 					nzcpy++
 				} else {
+					*dropped += sqr(abs(dense[irow]))
+					comp += dense[irow]
 					dense[irow] = 0
 				}
 			}
@@ -280,7 +286,9 @@ func lucopy(pivot pivotPolicy, pthresh, dthresh float64, nzcount int,
 
 			// Pattern + threshold dropping.
 
-			if pattern[irow] == 0 && irow != diagptr-1 && utemp < ldthreshabs {
+			if (pattern[irow] == 0 || drop != nil) && irow != diagptr-1 && utemp < ldthreshabs {
+				*dropped += sqr(utemp)
+				comp += dense[irow]
 				dense[irow] = 0
 			} else {
 				if irow == diagptr-1 {
@@ -305,6 +313,11 @@ func lucopy(pivot pivotPolicy, pthresh, dthresh float64, nzcount int,
 		return -1, fmt.Errorf("ujjptr not set (1) %v %v %v" /*diagptr*/, ujjptr, lcolst[jcol], ucolst[jcol+1]-1)
 	}
 
+	// Modified ILU: add the dropped elements to the diagonal.
+	if modified {
+		lu[ujjptr-off] += comp
+	}
+
 	pivrow := lurow[ujjptr-off]
 	ujj := lu[ujjptr-off]
 
@@ -341,6 +354,38 @@ func lucopy(pivot pivotPolicy, pthresh, dthresh float64, nzcount int,
 	return zpivot, nil
 }
 
+// dropRule drops the elements of a column of L or U with magnitude
+// less than tol times the 2-norm of the column of A and keeps at most
+// the fill largest of the remaining elements. Elements of U below the
+// tolerance are not used by lucomp, so that unless the fill limit
+// applies PAQ - LU consists of the dropped elements.
+type dropRule struct {
+	tol     float64
+	fill    int
+	colNorm float64
+}
+
+// threshold returns the smallest magnitude of the elements with
+// indexes lurow[nzst:nzend] that are kept.
+func (d *dropRule) threshold(lurow []int, nzst, nzend int, dense []float64, twork []float64) float64 {
+	thresh := d.tol * d.colNorm
+	if d.fill <= 0 || nzend-nzst <= d.fill {
+		return thresh
+	}
+	i := 0
+	for nzptr := nzst; nzptr < nzend; nzptr++ {
+		twork[i] = abs(dense[lurow[nzptr]-off])
+		i++
+	}
+	var kth float64
+	dordstat(i, i-d.fill+1, twork, &kth, &i)
+	return math.Max(thresh, kth)
+}
+
+func sqr(a float64) float64 {
+	return a * a
+}
+
 func abs(a float64) float64 {
 	return math.Abs(a)
 }
@@ -0,0 +1,56 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"math"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestSolveMany(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+
+	lu, err := gp.Factor(n, rowind, colst, nzA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const k = 21
+	b := make([]float64, n*k)
+	for i := range b {
+		b[i] = math.Sin(float64(i))
+	}
+
+	for _, trans := range []bool{false, true} {
+		// Serial solve of each column.
+		want := make([]float64, len(b))
+		copy(want, b)
+		rhs := make([][]float64, k)
+		for j := range rhs {
+			rhs[j] = want[j*n : (j+1)*n]
+		}
+		if err := gp.Solve(lu, rhs, trans); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, workers := range []int{1, 3, 0} {
+			x := make([]float64, len(b))
+			copy(x, b)
+			if err := gp.SolveMany(lu, x, k, trans, workers); err != nil {
+				t.Fatal(err)
+			}
+			for i := range x {
+				if x[i] != want[i] {
+					t.Fatalf("trans=%v workers=%d: x[%d] expected %v actual %v", trans, workers, i, want[i], x[i])
+				}
+			}
+		}
+	}
+
+	if err := gp.SolveMany(lu, b[1:], k, false, 1); err == nil {
+		t.Error("expected length error")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestParallel(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+	m := 20
+	lrowind, lcolst, lnzA := csc(laplacian(m))
+
+	for _, test := range []struct {
+		name   string
+		n      int
+		rowind []int
+		colst  []int
+		nzA    []float64
+		opts   []gp.OptFunc
+	}{
+		{"lhr01", n, rowind, colst, nzA, nil},
+		{"lhr01 threshold", n, rowind, colst, nzA, []gp.OptFunc{gp.PartialPivoting(0.1)}},
+		{"laplacian", m * m, lrowind, lcolst, lnzA, nil},
+		{"laplacian ilut", m * m, lrowind, lcolst, lnzA, []gp.OptFunc{gp.DropThreshold(0.1)}},
+	} {
+		x0 := make([]float64, test.n)
+		for i := range x0 {
+			x0[i] = float64(i%7) + 1
+		}
+		b := matVec(test.n, test.rowind, test.colst, test.nzA, x0)
+
+		lu, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA, test.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		want := make([]float64, test.n)
+		copy(want, b)
+		if err := gp.Solve(lu, [][]float64{want}, false); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, workers := range []int{2, 4, 0} {
+			opts := append([]gp.OptFunc{gp.Parallel(workers)}, test.opts...)
+			plu, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA, opts...)
+			if err != nil {
+				t.Fatalf("%s: workers %d: %v", test.name, workers, err)
+			}
+			x := make([]float64, test.n)
+			copy(x, b)
+			if err := gp.Solve(plu, [][]float64{x}, false); err != nil {
+				t.Fatal(err)
+			}
+			for i := range x {
+				if x[i] != want[i] {
+					t.Fatalf("%s: workers %d: x[%d] expected %v actual %v", test.name, workers, i, want[i], x[i])
+				}
+			}
+		}
+	}
+
+	if _, err := gp.Factor(n, rowind, colst, nzA, gp.Parallel(2), gp.RankDeficient(0)); err == nil {
+		t.Error("expected error for rank-deficient parallel factorization")
+	}
+}
+
+func TestParallelMaxLUNonzeros(t *testing.T) {
+	m := 30
+	n := m * m
+	rowind, colst, nzA := csc(laplacian(m))
+
+	lu, err := gp.Factor(n, rowind, colst, nzA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nnz := lu.NNZ()
+	b := make([]float64, n)
+	for i := range b {
+		b[i] = 1
+	}
+	b = matVec(n, rowind, colst, nzA, b)
+	want := append([]float64(nil), b...)
+	if err := gp.Solve(lu, [][]float64{want}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bound on fill exceeds the limit, but the actual fill does
+	// not, so the factorization succeeds.
+	plu, err := gp.Factor(n, rowind, colst, nzA, gp.Parallel(2), gp.MaxLUNonzeros(nnz+n+5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := append([]float64(nil), b...)
+	if err := gp.Solve(plu, [][]float64{x}, false); err != nil {
+		t.Fatal(err)
+	}
+	for i := range x {
+		if x[i] != want[i] {
+			t.Fatalf("x[%d] expected %v actual %v", i, want[i], x[i])
+		}
+	}
+
+	_, err = gp.Factor(n, rowind, colst, nzA, gp.Parallel(2), gp.MaxLUNonzeros(nnz/2))
+	e, ok := err.(*gp.MemoryLimitError)
+	if !ok {
+		t.Fatalf("expected *MemoryLimitError, got %v", err)
+	}
+	if e.Column <= 0 || e.Column >= n {
+		t.Errorf("column %v out of range", e.Column)
+	}
+	if e.NNZ <= 0 || e.NNZ > e.Limit {
+		t.Errorf("nnz %v out of range (0,%v]", e.NNZ, e.Limit)
+	}
+}
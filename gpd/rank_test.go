@@ -0,0 +1,71 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"math"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestRankDeficient(t *testing.T) {
+	// Columns 2 and 4 are linear combinations of columns 0, 1 and 3.
+	dense := [][]float64{
+		{4, 1, 6, 0, 4},
+		{1, 3, 7, 1, 2},
+		{0, 1, 2, 0, 0},
+		{2, 0, 2, 5, 7},
+		{0, 2, 4, 1, 1},
+	}
+	n := len(dense)
+
+	rowind, colptr, nz := csc(dense)
+
+	if _, err := gp.Factor(n, rowind, colptr, nz); err == nil {
+		t.Fatalf("expected singular matrix error")
+	}
+
+	lu, err := gp.Factor(n, rowind, colptr, nz, gp.RankDeficient(1e-12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lu.Rank() != 3 {
+		t.Fatalf("rank, expected 3 actual %v", lu.Rank())
+	}
+	if len(lu.Deferred()) != 2 || len(lu.UnusedRows()) != 2 {
+		t.Fatalf("deferred %v unused rows %v", lu.Deferred(), lu.UnusedRows())
+	}
+
+	const eps = 1e-12
+
+	for k, x := range lu.NullSpace() {
+		ax := matVec(n, rowind, colptr, nz, x)
+		for i := range ax {
+			if math.Abs(ax[i]) > eps {
+				t.Errorf("null vector %d: A*x[%d] = %v", k, i, ax[i])
+			}
+		}
+	}
+
+	// Solve a consistent system and check the basic solution.
+	x0 := []float64{1, 2, 0, 3, 0}
+	b := matVec(n, rowind, colptr, nz, x0)
+
+	x := append([]float64(nil), b...)
+	if err := gp.Solve(lu, [][]float64{x}, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, j := range lu.Deferred() {
+		if x[j] != 0 {
+			t.Errorf("x[%d] for deferred column, expected 0 actual %v", j, x[j])
+		}
+	}
+	ax := matVec(n, rowind, colptr, nz, x)
+	for i := range ax {
+		if math.Abs(ax[i]-b[i]) > eps {
+			t.Errorf("resid[%d], expected < %v actual %v", i, eps, math.Abs(ax[i]-b[i]))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestFactorResidual(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+
+	const eps = 1e-14
+
+	for _, test := range []struct {
+		name string
+		opts []gp.OptFunc
+	}{
+		{"compact", nil},
+		{"wide", []gp.OptFunc{gp.WideIndexes()}},
+		{"threshold", []gp.OptFunc{gp.PartialPivoting(0.1)}},
+		{"dense", []gp.OptFunc{gp.DenseThreshold(0.05)}},
+		{"supernodal", []gp.OptFunc{gp.Supernodal()}},
+	} {
+		lu, err := gp.Factor(n, rowind, colst, nzA, test.opts...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		resid, dropped, err := lu.FactorResidual(rowind, colst, nzA)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if resid > eps || dropped != 0 {
+			t.Errorf("%s: residual %v dropped %v", test.name, resid, dropped)
+		}
+	}
+
+	// A perturbed matrix has a residual of the size of the perturbation.
+	lu, err := gp.Factor(n, rowind, colst, nzA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	perturbed := append([]float64(nil), nzA...)
+	perturbed[0] *= 1 + 1e-6
+	resid, _, err := lu.FactorResidual(rowind, colst, perturbed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resid < 1e-12 || resid > 1e-6 {
+		t.Errorf("perturbed residual %v", resid)
+	}
+
+	// The dropped elements account for the residual of an incomplete
+	// factorization.
+	ilu, err := gp.NewILUT(n, rowind, colst, nzA, 1e-4, 50, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resid, dropped, err := ilu.LU().FactorResidual(rowind, colst, nzA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped <= 0 || resid <= eps {
+		t.Errorf("ILUT residual %v dropped %v", resid, dropped)
+	}
+
+	rowindR, colptrR, nzR := csc([][]float64{
+		{4, 1, 6, 0, 4},
+		{1, 3, 7, 1, 2},
+		{0, 1, 2, 0, 0},
+		{2, 0, 2, 5, 7},
+		{0, 2, 4, 1, 1},
+	})
+	rank, err := gp.Factor(5, rowindR, colptrR, nzR, gp.RankDeficient(1e-12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resid, _, err := rank.FactorResidual(rowindR, colptrR, nzR); err != nil || resid > eps {
+		t.Errorf("rank-deficient residual %v: %v", resid, err)
+	}
+
+	if _, _, err := lu.FactorResidual(rowind, colst[1:], nzA); err == nil {
+		t.Errorf("expected error for invalid colptr")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"math"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestFactorPartial(t *testing.T) {
+	dense := [][]float64{
+		{2.10, 0, 0, 0, 0, 0, 0, 0.14, 0.09, 0},
+		{0, 1.10, 0, 0, 0.06, 0, 0, 0, 0, 0.03},
+		{0, 0, 1.70, 0, 0, 0, 0, 0, 0, 0.04},
+		{0, 0, 0, 1.00, 0, 0, 0.32, 0.19, 0.32, 0.44},
+		{0, 0.06, 0, 0, 1.60, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 2.20, 0, 0, 0, 0},
+		{0, 0, 0, 0.32, 0, 0, 1.90, 0, 0, 0.43},
+		{0.14, 0, 0, 0.19, 0, 0, 0, 1.10, 0.22, 0},
+		{0.09, 0, 0, 0.32, 0, 0, 0, 0.22, 2.40, 0},
+		{0, 0.03, 0.04, 0.44, 0, 0, 0.43, 0, 0, 3.20},
+	}
+	n := len(dense)
+	rowind, colptr, nz := csc(dense)
+	colPerm := []int{6, 5, 2, 4, 1, 9, 7, 8, 0, 3}
+
+	for _, k := range []int{0, 4, 7, n} {
+		lu, s, err := gp.FactorPartial(n, rowind, colptr, nz, k, gp.ColPerm(colPerm))
+		if err != nil {
+			t.Fatalf("k=%d: %v", k, err)
+		}
+		if lu.Cols() != k || s.N != n-k {
+			t.Fatalf("k=%d: cols %v, schur order %v", k, lu.Cols(), s.N)
+		}
+		for j, c := range s.Cols {
+			if c != colPerm[k+j] {
+				t.Errorf("k=%d: schur col %d, expected %v actual %v", k, j, colPerm[k+j], c)
+			}
+		}
+
+		expected := schur(dense, colPerm[:k], s.Rows, s.Cols)
+		for j := 0; j < s.N; j++ {
+			actual := make([]float64, s.N)
+			for p := s.ColPtr[j]; p < s.ColPtr[j+1]; p++ {
+				actual[s.RowInd[p]] += s.NZ[p]
+			}
+			for i := range actual {
+				if math.Abs(actual[i]-expected[i][j]) > 1e-14 {
+					t.Errorf("k=%d: S(%d,%d), expected %v actual %v", k, i, j, expected[i][j], actual[i])
+				}
+			}
+		}
+	}
+}
+
+// schur eliminates the pivot columns from a dense copy of a using
+// pivot rows that are not in rows, and returns the remaining rows and
+// cols.
+func schur(a [][]float64, pivots, rows, cols []int) [][]float64 {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append([]float64(nil), a[i]...)
+	}
+	isSchurRow := make([]bool, n)
+	for _, r := range rows {
+		isSchurRow[r] = true
+	}
+	used := make([]bool, n)
+	for _, c := range pivots {
+		p := -1
+		for i := 0; i < n; i++ {
+			if !isSchurRow[i] && !used[i] && (p < 0 || math.Abs(m[i][c]) > math.Abs(m[p][c])) {
+				p = i
+			}
+		}
+		used[p] = true
+		for i := 0; i < n; i++ {
+			if used[i] || m[i][c] == 0 {
+				continue
+			}
+			l := m[i][c] / m[p][c]
+			for j := 0; j < n; j++ {
+				m[i][j] -= l * m[p][j]
+			}
+		}
+	}
+	s := make([][]float64, len(rows))
+	for i, r := range rows {
+		s[i] = make([]float64, len(cols))
+		for j, c := range cols {
+			s[i][j] = m[r][c]
+		}
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestSplitSolve(t *testing.T) {
+	n, rowind, colst, nzA := lhr01()
+
+	lu, err := gp.Factor(n, rowind, colst, nzA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x0 := make([]float64, n)
+	for i := range x0 {
+		x0[i] = 1
+	}
+
+	for _, trans := range []bool{false, true} {
+		var b []float64
+		if trans {
+			b = matTransVec(n, rowind, colst, nzA, x0)
+		} else {
+			b = matVec(n, rowind, colst, nzA, x0)
+		}
+
+		x := make([]float64, n)
+		copy(x, b)
+		if err := gp.Solve(lu, [][]float64{x}, trans); err != nil {
+			t.Fatal(err)
+		}
+
+		// The split solves work in place and reproduce Solve exactly.
+		y := make([]float64, n)
+		copy(y, b)
+		if trans {
+			err = lu.SolveUT(y, y)
+			if err == nil {
+				err = lu.SolveLT(y, y)
+			}
+		} else {
+			err = lu.SolveL(y, y)
+			if err == nil {
+				err = lu.SolveU(y, y)
+			}
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range x {
+			if x[i] != y[i] {
+				t.Fatalf("trans=%v: x[%d] expected %v actual %v", trans, i, x[i], y[i])
+			}
+		}
+	}
+}
+
+func TestSplitSolveFactors(t *testing.T) {
+	a := [][]float64{
+		{1, 2, 0},
+		{4, 0, 1},
+		{0, 3, 2},
+	}
+	n := len(a)
+	rowind, colptr, nz := csc(a)
+	lu, err := gp.Factor(n, rowind, colptr, nz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// For b = Ae the forward solve gives U Q'e and the backward solve
+	// recovers e.
+	e := []float64{1, 2, 3}
+	b := matVec(n, rowind, colptr, nz, e)
+	y := make([]float64, n)
+	if err := lu.SolveL(y, b); err != nil {
+		t.Fatal(err)
+	}
+	x := make([]float64, n)
+	if err := lu.SolveU(x, y); err != nil {
+		t.Fatal(err)
+	}
+	for i := range x {
+		if d := x[i] - e[i]; d > 1e-14 || d < -1e-14 {
+			t.Errorf("x[%d] expected %v actual %v", i, e[i], x[i])
+		}
+	}
+
+	if err := lu.SolveL(make([]float64, n-1), b); err == nil {
+		t.Error("expected length error")
+	}
+}
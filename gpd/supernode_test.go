@@ -0,0 +1,183 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+// testMatrix is a square matrix in compressed column form with
+// zero-based indexes.
+type testMatrix struct {
+	name          string
+	n             int
+	rowind, colst []int
+	nzA           []float64
+}
+
+// supernodeMatrices returns the test and benchmark matrices for
+// supernodal factorization.
+func supernodeMatrices(m2, m3, nc int) []testMatrix {
+	var tests []testMatrix
+	n, rowind, colst, nzA := lhr01()
+	tests = append(tests, testMatrix{"lhr01", n, rowind, colst, nzA})
+	n, rowind, colst, nzA = grid(m2, m2)
+	tests = append(tests, testMatrix{"2D", n, rowind, colst, nzA})
+	n, rowind, colst, nzA = grid(m3, m3, m3)
+	tests = append(tests, testMatrix{"3D", n, rowind, colst, nzA})
+	n, rowind, colst, nzA = circuit(nc)
+	tests = append(tests, testMatrix{"circuit", n, rowind, colst, nzA})
+	return tests
+}
+
+func TestSupernodal(t *testing.T) {
+	for _, test := range supernodeMatrices(40, 8, 500) {
+		x0 := make([]float64, test.n)
+		for i := range x0 {
+			x0[i] = float64(i%7) + 1
+		}
+		b := matVec(test.n, test.rowind, test.colst, test.nzA, x0)
+
+		for _, opts := range [][]gp.OptFunc{
+			{gp.Supernodal()},
+			{gp.Supernodal(), gp.PartialPivoting(0.1)},
+		} {
+			want, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA, opts[1:]...)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			lu, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA, opts...)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			if lu.Rank() != want.Rank() {
+				t.Errorf("%s: rank expected %d actual %d", test.name, want.Rank(), lu.Rank())
+			}
+
+			x := make([]float64, test.n)
+			copy(x, b)
+			if err := gp.Solve(lu, [][]float64{x}, false); err != nil {
+				t.Fatal(err)
+			}
+			for i := range x {
+				if math.Abs(x[i]-x0[i]) > 1e-8*math.Abs(x0[i]) {
+					t.Fatalf("%s: x[%d] expected %v actual %v", test.name, i, x0[i], x[i])
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkFactor(b *testing.B) {
+	for _, test := range supernodeMatrices(100, 18, 5000) {
+		for _, bench := range []struct {
+			name string
+			opts []gp.OptFunc
+		}{
+			{"scalar", nil},
+			{"supernodal", []gp.OptFunc{gp.Supernodal()}},
+			{"dense", []gp.OptFunc{gp.DenseThreshold(0.3)}},
+		} {
+			b.Run(test.name+"/"+bench.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					_, err := gp.Factor(test.n, test.rowind, test.colst, test.nzA, bench.opts...)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// grid returns the Laplacian on a grid with the given dimensions,
+// in compressed column form with zero-based indexes.
+func grid(dims ...int) (n int, rowind, colst []int, nzA []float64) {
+	n = 1
+	for _, d := range dims {
+		n *= d
+	}
+	for j := 0; j < n; j++ {
+		colst = append(colst, len(nzA))
+		lo := len(nzA)
+		rowind = append(rowind, j)
+		nzA = append(nzA, float64(2*len(dims)))
+		stride := 1
+		for _, d := range dims {
+			if (j/stride)%d != 0 {
+				rowind = append(rowind, j-stride)
+				nzA = append(nzA, -1)
+			}
+			if (j/stride)%d != d-1 {
+				rowind = append(rowind, j+stride)
+				nzA = append(nzA, -1)
+			}
+			stride *= d
+		}
+		sortRows(rowind[lo:], nzA[lo:])
+	}
+	colst = append(colst, len(nzA))
+	return
+}
+
+// circuit returns a random matrix with the structure of a circuit
+// simulation matrix: nodes coupled to a few neighbours on a chain and
+// a small number of supply nodes connected to many others.
+func circuit(n int) (int, []int, []int, []float64) {
+	rnd := rand.New(rand.NewSource(1))
+	dense := make([]map[int]float64, n)
+	for j := range dense {
+		dense[j] = make(map[int]float64)
+	}
+	couple := func(i, j int, g float64) {
+		dense[i][i] += g
+		dense[j][j] += g
+		dense[j][i] -= g
+		dense[i][j] -= g
+	}
+	for i := 0; i < n; i++ {
+		for k := 0; k < 2; k++ {
+			j := i + 1 + rnd.Intn(8)
+			if j < n {
+				couple(i, j, rnd.Float64()+0.1)
+			}
+		}
+	}
+	for s := n - 4; s < n; s++ {
+		for k := 0; k < n/20; k++ {
+			i := rnd.Intn(n - 4)
+			couple(i, s, rnd.Float64()+0.1)
+		}
+	}
+	for i := 0; i < n; i++ {
+		dense[i][i] += 0.01
+	}
+
+	var rowind, colst []int
+	var nzA []float64
+	for j := 0; j < n; j++ {
+		colst = append(colst, len(nzA))
+		lo := len(nzA)
+		for i, v := range dense[j] {
+			rowind = append(rowind, i)
+			nzA = append(nzA, v)
+		}
+		sortRows(rowind[lo:], nzA[lo:])
+	}
+	colst = append(colst, len(nzA))
+	return n, rowind, colst, nzA
+}
+
+func sortRows(rowind []int, nz []float64) {
+	for i := 1; i < len(rowind); i++ {
+		for k := i; k > 0 && rowind[k] < rowind[k-1]; k-- {
+			rowind[k], rowind[k-1] = rowind[k-1], rowind[k]
+			nz[k], nz[k-1] = nz[k-1], nz[k]
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 1988 John Gilbert and Tim Peierls
+// All rights reserved.
+
+package gpd_test
+
+import (
+	"testing"
+
+	gp "github.com/rwl/lufact/gpd"
+)
+
+func TestReplaceColumn(t *testing.T) {
+	dense := [][]float64{
+		{2.10, 0, 0, 0, 0, 0, 0, 0.14, 0.09, 0},
+		{0, 1.10, 0, 0, 0.06, 0, 0, 0, 0, 0.03},
+		{0, 0, 1.70, 0, 0, 0, 0, 0, 0, 0.04},
+		{0, 0, 0, 1.00, 0, 0, 0.32, 0.19, 0.32, 0.44},
+		{0, 0.06, 0, 0, 1.60, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 2.20, 0, 0, 0, 0},
+		{0, 0, 0, 0.32, 0, 0, 1.90, 0, 0, 0.43},
+		{0.14, 0, 0, 0.19, 0, 0, 0, 1.10, 0.22, 0},
+		{0.09, 0, 0, 0.32, 0, 0, 0, 0.22, 2.40, 0},
+		{0, 0.03, 0.04, 0.44, 0, 0, 0.43, 0, 0, 3.20},
+	}
+	n := len(dense)
+
+	rowind, colptr, nz := csc(dense)
+	lu, err := gp.Factor(n, rowind, colptr, nz)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for step, col := range []struct {
+		k    int
+		rows []int
+		vals []float64
+	}{
+		{3, []int{0, 3, 5, 9}, []float64{0.5, 1.5, 0.25, -1}},
+		{7, []int{1, 7}, []float64{0.3, 2.5}},
+		{3, []int{2, 3, 6}, []float64{1, 4, 0.1}},
+		{0, []int{0, 4, 8}, []float64{3, 0.2, -0.7}},
+		{9, []int{3, 9}, []float64{0.8, 1.2}},
+	} {
+		if err := lu.ReplaceColumn(col.k, col.rows, col.vals); err != nil {
+			t.Fatalf("replace[%d]: %v", step, err)
+		}
+		for i := range dense {
+			dense[i][col.k] = 0
+		}
+		for i, r := range col.rows {
+			dense[r][col.k] = col.vals[i]
+		}
+		rowind, colptr, nz = csc(dense)
+
+		for _, trans := range []bool{false, true} {
+			x0 := make([]float64, n)
+			for i := range x0 {
+				x0[i] = 1
+			}
+			var b []float64
+			if !trans {
+				b = matVec(n, rowind, colptr, nz, x0)
+			} else {
+				b = matTransVec(n, rowind, colptr, nz, x0)
+			}
+			if err := gp.Solve(lu, [][]float64{b}, trans); err != nil {
+				t.Fatalf("solve[%d]: %v", step, err)
+			}
+
+			const eps = 1e-12
+
+			resid := residual(b)
+			if resid > eps {
+				t.Errorf("resid[%d] (trans=%v), expected < %v actual %v", step, trans, eps, resid)
+			}
+		}
+	}
+	if lu.Updates() != 5 {
+		t.Errorf("updates, expected 5 actual %v", lu.Updates())
+	}
+	if lu.NeedsRefactor() {
+		t.Errorf("unexpected refactorization advice")
+	}
+
+	// Replacing a column with zeros is singular.
+	if err := lu.ReplaceColumn(1, nil, nil); err == nil {
+		t.Errorf("expected singular replacement error")
+	}
+}
+
+// csc returns the compressed sparse column form of a dense matrix.
+func csc(dense [][]float64) (rowind, colptr []int, nz []float64) {
+	n := len(dense)
+	for j := 0; j < n; j++ {
+		colptr = append(colptr, len(nz))
+		for i := 0; i < n; i++ {
+			if dense[i][j] != 0 {
+				rowind = append(rowind, i)
+				nz = append(nz, dense[i][j])
+			}
+		}
+	}
+	colptr = append(colptr, len(nz))
+	return
+}
+
+func matTransVec(n int, rowind, colst []int, nzA, x []float64) []float64 {
+	y := make([]float64, n)
+	for j := 0; j < n; j++ {
+		for ii := colst[j]; ii < colst[j+1]; ii++ {
+			y[j] += nzA[ii] * x[rowind[ii]]
+		}
+	}
+	return y
+}
+
+func TestReplaceColumnRefactor(t *testing.T) {
+	lu, err := gp.Factor(2, []int{0, 1}, []int{0, 1, 2}, []float64{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lu.ReplaceColumn(1, []int{0, 1}, []float64{1, 1e-10}); err != nil {
+		t.Fatal(err)
+	}
+	if lu.NeedsRefactor() {
+		t.Errorf("unexpected refactorization advice")
+	}
+	// Nearly dependent columns cause cancellation in the new diagonal.
+	if err := lu.ReplaceColumn(0, []int{0, 1}, []float64{1, 1.000000001e-10}); err != nil {
+		t.Fatal(err)
+	}
+	if !lu.NeedsRefactor() {
+		t.Errorf("expected refactorization advice")
+	}
+}
+
+func TestReplaceColumnSingular(t *testing.T) {
+	dense := [][]float64{
+		{4, 1, 0},
+		{1, 4, 1},
+		{0, 1, 4},
+	}
+	rowind, colptr, nz := csc(dense)
+	lu, err := gp.Factor(3, rowind, colptr, nz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lu.ReplaceColumn(1, nil, nil); err == nil {
+		t.Fatal("expected singular replacement error")
+	}
+
+	// A failed replacement leaves the factorization unchanged.
+	if lu.Updates() != 0 {
+		t.Errorf("updates, expected 0 actual %v", lu.Updates())
+	}
+	if _, err := lu.Factors(); err != nil {
+		t.Errorf("factors: %v", err)
+	}
+	x := []float64{1, 2, 3}
+	if err := lu.SolveL(x, x); err != nil {
+		t.Errorf("solve L: %v", err)
+	}
+	if err := lu.Check(); err != nil {
+		t.Errorf("check: %v", err)
+	}
+}
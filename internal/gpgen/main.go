@@ -21,22 +21,64 @@ var (
 	formatOutput = flag.Bool("fmt", true, "format generated files")
 
 	files = []string{
+		"analyze",
+		"append",
+		"binary",
+		"check",
+		"compact",
+		"dense",
 		"doc",
 		"factor",
+		"factors",
 		"gp",
-		"lsolve",
+		"ilu",
+		"iluk",
+		"levels",
+		"lowrank",
 		"lucomp",
 		"lucopy",
 		"ludfs",
 		//"lufact",
+		"many",
 		"maxmatch",
+		"parallel",
+		"rank",
+		"residual",
+		"schur",
+		"shift",
+		"split",
+		"supernode",
+		"update",
+	}
+
+	// indexFiles are generated once for each index type, with the
+	// int32 variant written to a file with the suffix "32".
+	indexFiles = []string{
+		"block",
+		"lsolve",
 		"usolve",
 	}
+
+	krylovFiles = []string{
+		"bicgstab",
+		"gmres",
+		"krylov",
+	}
 )
 
 type GPData struct {
 	Package    string
 	ScalarType string
+
+	// Prefix is prepended to the exported names of the krylov package
+	// and Blas to the unexported names and the file names.
+	Prefix string
+	Blas   string
+
+	// Index is the integer type of the index arrays and Suffix is
+	// appended to the function and file names of its variant.
+	Index  string
+	Suffix string
 }
 
 func (GPData) Header() string {
@@ -46,35 +88,57 @@ func (GPData) Header() string {
 // All rights reserved.`
 }
 
+// OwnHeader is the header of generated code that is not translated
+// from the routines of John Gilbert and Tim Peierls.
+func (GPData) OwnHeader() string {
+	return `// Code generated with gpgen. DO NOT EDIT.
+
+// Copyright 2018 Richard Lincoln. All rights reserved.`
+}
+
+// Int converts the index expression to int, if the index type is not int.
+func (d GPData) Int(expr string) string {
+	if d.Index == "int" {
+		return expr
+	}
+	return "int(" + expr + ")"
+}
+
 func execute() error {
 	for _, filename := range files {
 		tpath := fmt.Sprintf("%s/%s.tmpl", tmplDir, filename)
-		tmpl, err := template.ParseFiles(tpath)
-		if err != nil {
-			return err
-		}
 		for _, t := range []GPData{
-			{Package: "gpd", ScalarType: "float64"},
-			{Package: "gpz", ScalarType: "complex128"},
+			{Package: "gpd", ScalarType: "float64", Index: "int"},
+			{Package: "gpz", ScalarType: "complex128", Index: "int"},
 		} {
-			buf := new(bytes.Buffer)
-
-			if err := tmpl.Execute(buf, t); err != nil {
+			out := filepath.Join(outDir, t.Package, filename+".go")
+			if err := generate(tpath, out, t); err != nil {
 				return err
 			}
-
-			var code []byte
-			if *formatOutput {
-				code, err = format.Source(buf.Bytes())
-				if err != nil {
-					return err
-				}
-			} else {
-				code = buf.Bytes()
+		}
+	}
+	for _, filename := range indexFiles {
+		tpath := fmt.Sprintf("%s/%s.tmpl", tmplDir, filename)
+		for _, t := range []GPData{
+			{Package: "gpd", ScalarType: "float64", Index: "int"},
+			{Package: "gpd", ScalarType: "float64", Index: "int32", Suffix: "32"},
+			{Package: "gpz", ScalarType: "complex128", Index: "int"},
+			{Package: "gpz", ScalarType: "complex128", Index: "int32", Suffix: "32"},
+		} {
+			out := filepath.Join(outDir, t.Package, filename+t.Suffix+".go")
+			if err := generate(tpath, out, t); err != nil {
+				return err
 			}
-			out := filepath.Join(outDir, t.Package, filename+".go")
-			err := ioutil.WriteFile(out, code, 0644)
-			if err != nil {
+		}
+	}
+	for _, filename := range krylovFiles {
+		tpath := fmt.Sprintf("%s/krylov/%s.tmpl", tmplDir, filename)
+		for _, t := range []GPData{
+			{Package: "krylov", ScalarType: "float64", Blas: "d"},
+			{Package: "krylov", ScalarType: "complex128", Prefix: "Z", Blas: "z"},
+		} {
+			out := filepath.Join(outDir, t.Package, t.Blas+filename+".go")
+			if err := generate(tpath, out, t); err != nil {
 				return err
 			}
 		}
@@ -82,6 +146,29 @@ func execute() error {
 	return nil
 }
 
+func generate(tpath, out string, t GPData) error {
+	tmpl, err := template.ParseFiles(tpath)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+
+	if err := tmpl.Execute(buf, t); err != nil {
+		return err
+	}
+
+	var code []byte
+	if *formatOutput {
+		code, err = format.Source(buf.Bytes())
+		if err != nil {
+			return err
+		}
+	} else {
+		code = buf.Bytes()
+	}
+	return ioutil.WriteFile(out, code, 0644)
+}
+
 func main() {
 	err := execute()
 	if err != nil {
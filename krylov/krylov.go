@@ -0,0 +1,111 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+// Package krylov provides preconditioned Krylov subspace methods for
+// solving sparse systems of linear equations Ax = b.
+//
+// The methods take the matrix A as an Operator and the preconditioner
+// M as a Preconditioner, which is satisfied by the complete and
+// incomplete factorizations of package gpd. The functions and types
+// with a Z prefix are the complex128 versions, for use with package
+// gpz.
+//
+// The float64 and complex128 versions are generated from templates in
+// internal/gpgen.
+package krylov
+
+import "fmt"
+
+// Settings holds the parameters of an iterative solve. The zero value
+// selects the defaults.
+type Settings struct {
+	// Tol is the relative residual tolerance. The iteration stops
+	// when ||b - Ax|| <= Tol*||b||, or Tol if b is zero. The default
+	// is 1e-8.
+	Tol float64
+
+	// MaxIter is the maximum number of iterations, that is of
+	// applications of the operator and preconditioner. The default
+	// is 10*n.
+	MaxIter int
+
+	// Restart is the number of iterations between restarts of GMRES
+	// and FGMRES. The default is min(n, 30).
+	Restart int
+}
+
+// defaults returns the settings with defaults applied for a system
+// of order n.
+func (s Settings) defaults(n int) (Settings, error) {
+	if s.Tol < 0 {
+		return s, fmt.Errorf("tolerance (%v) must be >= 0", s.Tol)
+	}
+	if s.MaxIter < 0 {
+		return s, fmt.Errorf("max iterations (%v) must be >= 0", s.MaxIter)
+	}
+	if s.Restart < 0 {
+		return s, fmt.Errorf("restart (%v) must be >= 0", s.Restart)
+	}
+	if s.Tol == 0 {
+		s.Tol = 1e-8
+	}
+	if s.MaxIter == 0 {
+		s.MaxIter = 10 * n
+	}
+	if s.Restart == 0 {
+		s.Restart = 30
+		if n < s.Restart {
+			s.Restart = n
+		}
+	}
+	if s.Restart == 0 {
+		s.Restart = 1
+	}
+	return s, nil
+}
+
+// Status describes how an iterative solve ended.
+type Status int
+
+const (
+	// Converged indicates that the residual tolerance was met.
+	Converged Status = iota + 1
+
+	// MaxIterations indicates that the iteration limit was reached.
+	MaxIterations
+
+	// Breakdown indicates that the method could not continue,
+	// for example because of a zero inner product in BiCGSTAB.
+	Breakdown
+
+	// Cancelled indicates that the context was cancelled.
+	Cancelled
+)
+
+func (s Status) String() string {
+	switch s {
+	case Converged:
+		return "converged"
+	case MaxIterations:
+		return "maximum iterations"
+	case Breakdown:
+		return "breakdown"
+	case Cancelled:
+		return "cancelled"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Result holds the outcome of an iterative solve.
+type Result struct {
+	// Iterations is the number of iterations performed.
+	Iterations int
+
+	// Residuals is the history of the residual 2-norm, starting with
+	// the initial residual. For GMRES and FGMRES the values within a
+	// cycle are the estimates given by the least squares problem.
+	Residuals []float64
+
+	// Status describes how the solve ended. It is zero if an error
+	// other than cancellation occurred.
+	Status Status
+}
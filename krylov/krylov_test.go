@@ -0,0 +1,215 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package krylov_test
+
+import (
+	"context"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/rwl/lufact/gpd"
+	"github.com/rwl/lufact/gpz"
+	"github.com/rwl/lufact/krylov"
+)
+
+// csc is a sparse matrix in compressed column format.
+type csc struct {
+	n      int
+	rowind []int
+	colptr []int
+	nz     []float64
+}
+
+func (a *csc) MulVec(dst, x []float64) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	for j := 0; j < a.n; j++ {
+		for p := a.colptr[j]; p < a.colptr[j+1]; p++ {
+			dst[a.rowind[p]] += a.nz[p] * x[j]
+		}
+	}
+}
+
+// zcsc is a complex sparse matrix in compressed column format.
+type zcsc struct {
+	n      int
+	rowind []int
+	colptr []int
+	nz     []complex128
+}
+
+func (a *zcsc) MulVec(dst, x []complex128) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	for j := 0; j < a.n; j++ {
+		for p := a.colptr[j]; p < a.colptr[j+1]; p++ {
+			dst[a.rowind[p]] += a.nz[p] * x[j]
+		}
+	}
+}
+
+// convDiff returns the 5-point convection-diffusion operator with
+// convection c on an m by m grid, with diagonal d.
+func convDiff(m int, c float64, d complex128) *zcsc {
+	n := m * m
+	a := &zcsc{n: n, colptr: make([]int, n+1)}
+	for j := 0; j < n; j++ {
+		add := func(i int, v complex128) {
+			a.rowind = append(a.rowind, i)
+			a.nz = append(a.nz, v)
+		}
+		if j >= m {
+			add(j-m, complex(-1-c, 0))
+		}
+		if j%m != 0 {
+			add(j-1, complex(-1-c, 0))
+		}
+		add(j, d)
+		if j%m != m-1 {
+			add(j+1, complex(-1+c, 0))
+		}
+		if j < n-m {
+			add(j+m, complex(-1+c, 0))
+		}
+		a.colptr[j+1] = len(a.nz)
+	}
+	return a
+}
+
+func (a *zcsc) real() *csc {
+	nz := make([]float64, len(a.nz))
+	for i, v := range a.nz {
+		nz[i] = real(v)
+	}
+	return &csc{n: a.n, rowind: a.rowind, colptr: a.colptr, nz: nz}
+}
+
+func TestSolvers(t *testing.T) {
+	a := convDiff(20, 0.4, 4).real()
+	n := a.n
+
+	x0 := make([]float64, n)
+	for i := range x0 {
+		x0[i] = math.Sin(float64(i))
+	}
+	b := make([]float64, n)
+	a.MulVec(b, x0)
+
+	lu, err := gpd.Factor(n, a.rowind, a.colptr, a.nz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ilu, err := gpd.NewILUT(n, a.rowind, a.colptr, a.nz, 1e-2, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type solver func(context.Context, krylov.Operator, []float64, []float64, krylov.Preconditioner, krylov.Settings) (krylov.Result, error)
+	for _, test := range []struct {
+		name     string
+		solve    solver
+		settings krylov.Settings
+	}{
+		{"GMRES", krylov.GMRES, krylov.Settings{Restart: 20}},
+		{"FGMRES", krylov.FGMRES, krylov.Settings{Restart: 20}},
+		{"BiCGSTAB", krylov.BiCGSTAB, krylov.Settings{}},
+	} {
+		var iters []int
+		for _, m := range []krylov.Preconditioner{nil, ilu, lu} {
+			x := make([]float64, n)
+			res, err := test.solve(context.Background(), a, b, x, m, test.settings)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			if res.Status != krylov.Converged {
+				t.Fatalf("%s: status %v after %d iterations", test.name, res.Status, res.Iterations)
+			}
+			if len(res.Residuals) != res.Iterations+1 {
+				t.Errorf("%s: len residuals (%v) must be iterations+1 (%v)", test.name, len(res.Residuals), res.Iterations+1)
+			}
+			var e float64
+			for i := range x {
+				e = math.Max(e, math.Abs(x[i]-x0[i]))
+			}
+			if e > 1e-6 {
+				t.Errorf("%s: error %v", test.name, e)
+			}
+			iters = append(iters, res.Iterations)
+		}
+		if !(iters[0] > iters[1] && iters[1] > iters[2] && iters[2] <= 2) {
+			t.Errorf("%s: iterations none/ilu/lu %v", test.name, iters)
+		}
+	}
+}
+
+func TestComplexSolvers(t *testing.T) {
+	a := convDiff(15, 0.2, complex(4, 0.5))
+	n := a.n
+
+	x0 := make([]complex128, n)
+	for i := range x0 {
+		x0[i] = cmplx.Exp(complex(0, float64(i)))
+	}
+	b := make([]complex128, n)
+	a.MulVec(b, x0)
+
+	ilu, err := gpz.NewILUT(n, a.rowind, a.colptr, a.nz, 1e-2, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type solver func(context.Context, krylov.ZOperator, []complex128, []complex128, krylov.ZPreconditioner, krylov.Settings) (krylov.Result, error)
+	for _, test := range []struct {
+		name  string
+		solve solver
+	}{
+		{"ZGMRES", krylov.ZGMRES},
+		{"ZFGMRES", krylov.ZFGMRES},
+		{"ZBiCGSTAB", krylov.ZBiCGSTAB},
+	} {
+		x := make([]complex128, n)
+		res, err := test.solve(context.Background(), a, b, x, ilu, krylov.Settings{Tol: 1e-10})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if res.Status != krylov.Converged {
+			t.Fatalf("%s: status %v after %d iterations", test.name, res.Status, res.Iterations)
+		}
+		var e float64
+		for i := range x {
+			e = math.Max(e, cmplx.Abs(x[i]-x0[i]))
+		}
+		if e > 1e-8 {
+			t.Errorf("%s: error %v", test.name, e)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	a := convDiff(10, 0, 4).real()
+	b := make([]float64, a.n)
+	for i := range b {
+		b[i] = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	x := make([]float64, a.n)
+	res, err := krylov.GMRES(ctx, a, b, x, nil, krylov.Settings{})
+	if err != context.Canceled || res.Status != krylov.Cancelled {
+		t.Errorf("GMRES: status %v, err %v", res.Status, err)
+	}
+	res, err = krylov.BiCGSTAB(ctx, a, b, x, nil, krylov.Settings{})
+	if err != context.Canceled || res.Status != krylov.Cancelled {
+		t.Errorf("BiCGSTAB: status %v, err %v", res.Status, err)
+	}
+
+	// The iteration limit is reported as a status, not an error.
+	res, err = krylov.GMRES(context.Background(), a, b, x, nil, krylov.Settings{MaxIter: 3, Tol: 1e-14})
+	if err != nil || res.Status != krylov.MaxIterations || res.Iterations != 3 {
+		t.Errorf("GMRES: status %v after %d iterations, err %v", res.Status, res.Iterations, err)
+	}
+}
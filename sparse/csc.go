@@ -0,0 +1,273 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+// Package sparse holds sparse matrices in the compressed column form
+// used by Factor in packages gpd and gpz. Its subpackages read and
+// write sparse matrix file formats.
+package sparse
+
+import (
+	"errors"
+	"fmt"
+	"math/cmplx"
+)
+
+// CSC is a sparse matrix in compressed column form with zero-based
+// indexes. The row indexes of the elements of column j are
+// RowInd[ColPtr[j]:ColPtr[j+1]]. Values holds the elements of a real
+// matrix and ZValues those of a complex matrix. Both are nil for a
+// pattern matrix.
+type CSC struct {
+	Rows, Cols int
+	ColPtr     []int
+	RowInd     []int
+	Values     []float64
+	ZValues    []complex128
+}
+
+// Symmetry is the symmetry of a matrix of which only the lower
+// triangle is stored.
+type Symmetry int
+
+const (
+	General Symmetry = iota
+	Symmetric
+	SkewSymmetric
+	Hermitian
+)
+
+// NNZ returns the number of stored elements.
+func (a *CSC) NNZ() int {
+	return a.ColPtr[a.Cols]
+}
+
+// IsComplex returns true if a has complex values.
+func (a *CSC) IsComplex() bool {
+	return a.ZValues != nil
+}
+
+// IsPattern returns true if a has no values.
+func (a *CSC) IsPattern() bool {
+	return a.Values == nil && a.ZValues == nil
+}
+
+// Check returns an error if the arrays of a are inconsistent.
+func (a *CSC) Check() error {
+	if a.Rows < 0 || a.Cols < 0 {
+		return fmt.Errorf("invalid dimensions %v by %v", a.Rows, a.Cols)
+	}
+	if len(a.ColPtr) != a.Cols+1 || a.ColPtr[0] != 0 {
+		return fmt.Errorf("len ColPtr (%v) must be Cols+1 (%v)", len(a.ColPtr), a.Cols+1)
+	}
+	for j := 0; j < a.Cols; j++ {
+		if a.ColPtr[j] > a.ColPtr[j+1] {
+			return fmt.Errorf("column pointers decrease at column %v", j)
+		}
+	}
+	nnz := a.ColPtr[a.Cols]
+	if len(a.RowInd) != nnz {
+		return fmt.Errorf("len RowInd (%v) must be nnz (%v)", len(a.RowInd), nnz)
+	}
+	if a.Values != nil && a.ZValues != nil {
+		return errors.New("matrix must not have both real and complex values")
+	}
+	if a.Values != nil && len(a.Values) != nnz {
+		return fmt.Errorf("len Values (%v) must be nnz (%v)", len(a.Values), nnz)
+	}
+	if a.ZValues != nil && len(a.ZValues) != nnz {
+		return fmt.Errorf("len ZValues (%v) must be nnz (%v)", len(a.ZValues), nnz)
+	}
+	for p, i := range a.RowInd {
+		if i < 0 || i >= a.Rows {
+			return fmt.Errorf("row index %v at %v out of range [0,%d)", i, p, a.Rows)
+		}
+	}
+	return nil
+}
+
+// FromCOO returns the matrix with the given elements in coordinate
+// form. The rows of each column are sorted and duplicate elements are
+// summed. Either values or zvalues may be given, or neither for a
+// pattern matrix.
+func FromCOO(rows, cols int, rowind, colind []int, values []float64, zvalues []complex128) (*CSC, error) {
+	if rows < 0 || cols < 0 {
+		return nil, fmt.Errorf("invalid dimensions %v by %v", rows, cols)
+	}
+	nnz := len(rowind)
+	if len(colind) != nnz || (values != nil && len(values) != nnz) || (zvalues != nil && len(zvalues) != nnz) {
+		return nil, errors.New("coordinate arrays must have the same length")
+	}
+	for k := 0; k < nnz; k++ {
+		if rowind[k] < 0 || rowind[k] >= rows || colind[k] < 0 || colind[k] >= cols {
+			return nil, fmt.Errorf("element (%v,%v) out of range", rowind[k], colind[k])
+		}
+	}
+
+	// Sort by row and then, stably, by column.
+	rowPtr := count(rowind, rows)
+	byRow := make([]int, nnz)
+	for k, i := range rowind {
+		byRow[rowPtr[i]] = k
+		rowPtr[i]++
+	}
+	colPtr := count(colind, cols)
+	order := make([]int, nnz)
+	for _, k := range byRow {
+		order[colPtr[colind[k]]] = k
+		colPtr[colind[k]]++
+	}
+
+	a := &CSC{Rows: rows, Cols: cols, ColPtr: make([]int, cols+1), RowInd: make([]int, 0, nnz)}
+	if values != nil {
+		a.Values = make([]float64, 0, nnz)
+	}
+	if zvalues != nil {
+		a.ZValues = make([]complex128, 0, nnz)
+	}
+	for p := 0; p < len(order); {
+		j, i := colind[order[p]], rowind[order[p]]
+		var v float64
+		var z complex128
+		for ; p < len(order) && colind[order[p]] == j && rowind[order[p]] == i; p++ {
+			if values != nil {
+				v += values[order[p]]
+			}
+			if zvalues != nil {
+				z += zvalues[order[p]]
+			}
+		}
+		a.RowInd = append(a.RowInd, i)
+		if values != nil {
+			a.Values = append(a.Values, v)
+		}
+		if zvalues != nil {
+			a.ZValues = append(a.ZValues, z)
+		}
+		a.ColPtr[j+1]++
+	}
+	for j := 0; j < cols; j++ {
+		a.ColPtr[j+1] += a.ColPtr[j]
+	}
+	return a, nil
+}
+
+// count returns the starting position of each index in a counting
+// sort of ind.
+func count(ind []int, n int) []int {
+	ptr := make([]int, n+1)
+	for _, i := range ind {
+		ptr[i+1]++
+	}
+	for i := 0; i < n; i++ {
+		ptr[i+1] += ptr[i]
+	}
+	return ptr[:n]
+}
+
+// Permutation returns the permutation matrix with a one in row perm[j]
+// of each column j.
+func Permutation(perm []int) (*CSC, error) {
+	n := len(perm)
+	p := &CSC{Rows: n, Cols: n, ColPtr: make([]int, n+1), RowInd: make([]int, n), Values: make([]float64, n)}
+	seen := make([]bool, n)
+	for j, i := range perm {
+		if i < 0 || i >= n || seen[i] {
+			return nil, fmt.Errorf("invalid permutation at %v", j)
+		}
+		seen[i] = true
+		p.ColPtr[j+1] = j + 1
+		p.RowInd[j] = i
+		p.Values[j] = 1
+	}
+	return p, nil
+}
+
+// Transpose returns the transpose, not conjugated, of a with the rows
+// of each column sorted. A matrix in compressed row form is the
+// transpose of the matrix in compressed column form with the same
+// arrays.
+func (a *CSC) Transpose() *CSC {
+	nnz := a.NNZ()
+	t := &CSC{Rows: a.Cols, Cols: a.Rows, ColPtr: make([]int, a.Rows+1), RowInd: make([]int, nnz)}
+	if a.Values != nil {
+		t.Values = make([]float64, nnz)
+	}
+	if a.ZValues != nil {
+		t.ZValues = make([]complex128, nnz)
+	}
+	next := count(a.RowInd, a.Rows)
+	copy(t.ColPtr, next)
+	t.ColPtr[a.Rows] = nnz
+	for j := 0; j < a.Cols; j++ {
+		for p := a.ColPtr[j]; p < a.ColPtr[j+1]; p++ {
+			q := next[a.RowInd[p]]
+			next[a.RowInd[p]]++
+			t.RowInd[q] = j
+			if a.Values != nil {
+				t.Values[q] = a.Values[p]
+			}
+			if a.ZValues != nil {
+				t.ZValues[q] = a.ZValues[p]
+			}
+		}
+	}
+	return t
+}
+
+// Expand returns the full matrix of which a holds the lower triangle
+// with the given symmetry. Elements above the diagonal are ignored.
+func (a *CSC) Expand(s Symmetry) (*CSC, error) {
+	if s == General {
+		return a, nil
+	}
+	if a.Rows != a.Cols {
+		return nil, fmt.Errorf("symmetric matrix must be square (%v by %v)", a.Rows, a.Cols)
+	}
+	var rowind, colind []int
+	var values []float64
+	var zvalues []complex128
+	for j := 0; j < a.Cols; j++ {
+		for p := a.ColPtr[j]; p < a.ColPtr[j+1]; p++ {
+			i := a.RowInd[p]
+			if i < j {
+				continue
+			}
+			rowind = append(rowind, i)
+			colind = append(colind, j)
+			if a.Values != nil {
+				values = append(values, a.Values[p])
+			}
+			if a.ZValues != nil {
+				zvalues = append(zvalues, a.ZValues[p])
+			}
+			if i == j {
+				continue
+			}
+			rowind = append(rowind, j)
+			colind = append(colind, i)
+			if a.Values != nil {
+				v := a.Values[p]
+				if s == SkewSymmetric {
+					v = -v
+				}
+				values = append(values, v)
+			}
+			if a.ZValues != nil {
+				z := a.ZValues[p]
+				switch s {
+				case SkewSymmetric:
+					z = -z
+				case Hermitian:
+					z = cmplx.Conj(z)
+				}
+				zvalues = append(zvalues, z)
+			}
+		}
+	}
+	if a.Values != nil && values == nil {
+		values = []float64{}
+	}
+	if a.ZValues != nil && zvalues == nil {
+		zvalues = []complex128{}
+	}
+	return FromCOO(a.Rows, a.Cols, rowind, colind, values, zvalues)
+}
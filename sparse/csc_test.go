@@ -0,0 +1,104 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package sparse_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rwl/lufact/sparse"
+)
+
+func TestFromCOO(t *testing.T) {
+	// Unsorted, with a duplicate of (0,1).
+	a, err := sparse.FromCOO(3, 2,
+		[]int{2, 0, 1, 0, 0},
+		[]int{0, 1, 0, 0, 1},
+		[]float64{1, 2, 3, 4, 5}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &sparse.CSC{
+		Rows: 3, Cols: 2,
+		ColPtr: []int{0, 3, 4},
+		RowInd: []int{0, 1, 2, 0},
+		Values: []float64{4, 3, 1, 7},
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("got %+v, want %+v", a, want)
+	}
+	if err := a.Check(); err != nil {
+		t.Error(err)
+	}
+
+	at := a.Transpose()
+	wantT := &sparse.CSC{
+		Rows: 2, Cols: 3,
+		ColPtr: []int{0, 2, 3, 4},
+		RowInd: []int{0, 1, 0, 0},
+		Values: []float64{4, 7, 3, 1},
+	}
+	if !reflect.DeepEqual(at, wantT) {
+		t.Errorf("transpose got %+v, want %+v", at, wantT)
+	}
+	if !reflect.DeepEqual(at.Transpose(), a) {
+		t.Errorf("transpose of transpose differs")
+	}
+
+	if _, err := sparse.FromCOO(2, 2, []int{2}, []int{0}, nil, nil); err == nil {
+		t.Errorf("expected error for row out of range")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	// Lower triangle of a 3 by 3 matrix.
+	lower := &sparse.CSC{
+		Rows: 3, Cols: 3,
+		ColPtr:  []int{0, 2, 3, 3},
+		RowInd:  []int{0, 2, 2},
+		ZValues: []complex128{1, 2 + 1i, 3i},
+	}
+	for _, test := range []struct {
+		sym  sparse.Symmetry
+		want []complex128
+	}{
+		{sparse.Symmetric, []complex128{1, 2 + 1i, 3i, 2 + 1i, 3i}},
+		{sparse.SkewSymmetric, []complex128{1, 2 + 1i, 3i, -2 - 1i, -3i}},
+		{sparse.Hermitian, []complex128{1, 2 + 1i, 3i, 2 - 1i, -3i}},
+	} {
+		a, err := lower.Expand(test.sym)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{0, 2, 3, 5}; !reflect.DeepEqual(a.ColPtr, want) {
+			t.Errorf("%v: colptr %v, want %v", test.sym, a.ColPtr, want)
+		}
+		if want := []int{0, 2, 2, 0, 1}; !reflect.DeepEqual(a.RowInd, want) {
+			t.Errorf("%v: rowind %v, want %v", test.sym, a.RowInd, want)
+		}
+		if !reflect.DeepEqual(a.ZValues, test.want) {
+			t.Errorf("%v: values %v, want %v", test.sym, a.ZValues, test.want)
+		}
+	}
+}
+
+func TestPermutation(t *testing.T) {
+	p, err := sparse.Permutation([]int{2, 0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &sparse.CSC{
+		Rows: 3, Cols: 3,
+		ColPtr: []int{0, 1, 2, 3},
+		RowInd: []int{2, 0, 1},
+		Values: []float64{1, 1, 1},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("got %+v, want %+v", p, want)
+	}
+	for _, perm := range [][]int{{0, 0}, {1, 2}, {-1}} {
+		if _, err := sparse.Permutation(perm); err == nil {
+			t.Errorf("expected error for %v", perm)
+		}
+	}
+}
@@ -0,0 +1,437 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+// Package hb reads and writes matrices in the fixed-format
+// Harwell-Boeing and Rutherford-Boeing exchange formats.
+package hb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rwl/lufact/sparse"
+)
+
+// File holds the contents of a Harwell-Boeing file.
+type File struct {
+	Title string
+	Key   string
+
+	// Type is the matrix type, such as RUA. The first letter is R
+	// for real, C for complex, I for integer (Rutherford-Boeing only)
+	// or P for pattern, and the second S for symmetric, U for
+	// unsymmetric, H for Hermitian, Z for skew-symmetric or R for
+	// rectangular. Only assembled matrices, with the third letter A,
+	// are supported.
+	Type string
+
+	// A is the matrix as stored, with only the lower triangle for the
+	// symmetric types. Integer matrices are returned with Values.
+	A *sparse.CSC
+
+	// RHS holds the optional right-hand sides, Guess the starting
+	// guesses and Exact the exact solutions, as matrices with the rows
+	// of A and a column for each right-hand side. Guess and Exact are
+	// stored full. Their values are complex for complex matrices and
+	// real otherwise. The Rutherford-Boeing format has no right-hand
+	// sides.
+	RHS   *sparse.CSC
+	Guess *sparse.CSC
+	Exact *sparse.CSC
+}
+
+// Symmetry returns the symmetry of the matrix type.
+func (f *File) Symmetry() sparse.Symmetry {
+	switch f.Type[1] {
+	case 'S':
+		return sparse.Symmetric
+	case 'H':
+		return sparse.Hermitian
+	case 'Z':
+		return sparse.SkewSymmetric
+	}
+	return sparse.General
+}
+
+// Matrix returns the full matrix, expanding the symmetric types.
+func (f *File) Matrix() (*sparse.CSC, error) {
+	return f.A.Expand(f.Symmetry())
+}
+
+// Read reads a matrix, and any right-hand sides, in Harwell-Boeing or
+// Rutherford-Boeing format.
+func Read(r io.Reader) (*File, error) {
+	s := &scanner{s: bufio.NewScanner(r)}
+	s.s.Buffer(nil, 1<<20)
+
+	title, err := s.line()
+	if err != nil {
+		return nil, err
+	}
+	f := &File{
+		Title: strings.TrimSpace(column(title, 0, 72)),
+		Key:   strings.TrimSpace(column(title, 72, 80)),
+	}
+
+	// Rutherford-Boeing files omit the count of right-hand side lines.
+	line, err := s.line()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseInts(line, 4); err != nil {
+		return nil, s.errorf("%v", err)
+	}
+	var rhscrd int
+	if counts := strings.Fields(line); len(counts) > 4 {
+		if rhscrd, err = strconv.Atoi(counts[4]); err != nil || rhscrd < 0 {
+			return nil, s.errorf("invalid integer %q", counts[4])
+		}
+	}
+
+	if line, err = s.line(); err != nil {
+		return nil, err
+	}
+	f.Type = strings.ToUpper(column(line, 0, 3))
+	if len(f.Type) != 3 || !strings.ContainsRune("RCIP", rune(f.Type[0])) ||
+		!strings.ContainsRune("SUHZR", rune(f.Type[1])) {
+		return nil, s.errorf("invalid matrix type %q", f.Type)
+	}
+	if f.Type[2] != 'A' {
+		return nil, s.errorf("unsupported matrix type %q", f.Type)
+	}
+	dims, err := parseInts(column(line, 3, len(line)), 3)
+	if err != nil {
+		return nil, s.errorf("%v", err)
+	}
+	nrow, ncol, nnz := dims[0], dims[1], dims[2]
+
+	line, err = s.line()
+	if err != nil {
+		return nil, err
+	}
+	ptrfmt, err := parseFormat(column(line, 0, 16))
+	if err != nil {
+		return nil, s.errorf("pointer format: %v", err)
+	}
+	indfmt, err := parseFormat(column(line, 16, 32))
+	if err != nil {
+		return nil, s.errorf("index format: %v", err)
+	}
+	var valfmt format
+	if f.Type[0] != 'P' {
+		if valfmt, err = parseFormat(column(line, 32, 52)); err != nil {
+			return nil, s.errorf("value format: %v", err)
+		}
+	}
+	var rhs rhsHeader
+	if rhscrd > 0 {
+		if rhs.format, err = parseFormat(column(line, 52, 72)); err != nil {
+			return nil, s.errorf("right-hand side format: %v", err)
+		}
+		if line, err = s.line(); err != nil {
+			return nil, err
+		}
+		if rhs, err = parseRHSHeader(line, rhs.format); err != nil {
+			return nil, s.errorf("%v", err)
+		}
+	}
+
+	a := &sparse.CSC{Rows: nrow, Cols: ncol}
+	if a.ColPtr, err = s.indexes(ptrfmt, ncol+1); err != nil {
+		return nil, err
+	}
+	if a.RowInd, err = s.indexes(indfmt, nnz); err != nil {
+		return nil, err
+	}
+	cplx := f.Type[0] == 'C'
+	if f.Type[0] != 'P' {
+		if a.Values, a.ZValues, err = s.values(valfmt, nnz, cplx); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.Check(); err != nil {
+		return nil, err
+	}
+	f.A = a
+
+	if rhscrd > 0 {
+		if err := s.rhs(f, &rhs, ptrfmt, indfmt, cplx); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// rhsHeader is the description of the right-hand sides on the fifth
+// line of a Harwell-Boeing file.
+type rhsHeader struct {
+	typ    string
+	nrhs   int
+	nrhsix int
+	format format
+}
+
+func parseRHSHeader(line string, f format) (rhsHeader, error) {
+	h := rhsHeader{typ: strings.ToUpper(column(line, 0, 3)), format: f}
+	if len(h.typ) < 1 || (h.typ[0] != 'F' && h.typ[0] != 'M') {
+		return h, fmt.Errorf("invalid right-hand side type %q", h.typ)
+	}
+	n, err := parseInts(column(line, 3, len(line)), 1)
+	if err != nil {
+		return h, err
+	}
+	h.nrhs = n[0]
+	if h.typ[0] == 'M' {
+		if n, err = parseInts(column(line, 3, len(line)), 2); err != nil {
+			return h, err
+		}
+		h.nrhsix = n[1]
+	}
+	return h, nil
+}
+
+// rhs reads the right-hand sides, followed by the guesses if the
+// second letter of the type is G and the exact solutions if the third
+// is X.
+func (s *scanner) rhs(f *File, h *rhsHeader, ptrfmt, indfmt format, cplx bool) error {
+	n := f.A.Rows
+	var err error
+	if h.typ[0] == 'F' {
+		if f.RHS, err = s.full(h.format, n, h.nrhs, cplx); err != nil {
+			return err
+		}
+	} else {
+		b := &sparse.CSC{Rows: n, Cols: h.nrhs}
+		if b.ColPtr, err = s.indexes(ptrfmt, h.nrhs+1); err != nil {
+			return err
+		}
+		if b.RowInd, err = s.indexes(indfmt, h.nrhsix); err != nil {
+			return err
+		}
+		if b.Values, b.ZValues, err = s.values(h.format, h.nrhsix, cplx); err != nil {
+			return err
+		}
+		if err := b.Check(); err != nil {
+			return fmt.Errorf("right-hand side: %v", err)
+		}
+		f.RHS = b
+	}
+	if len(h.typ) > 1 && h.typ[1] == 'G' {
+		if f.Guess, err = s.full(h.format, n, h.nrhs, cplx); err != nil {
+			return err
+		}
+	}
+	if len(h.typ) > 2 && h.typ[2] == 'X' {
+		if f.Exact, err = s.full(h.format, n, h.nrhs, cplx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// full reads a rows by cols matrix stored by columns.
+func (s *scanner) full(f format, rows, cols int, cplx bool) (*sparse.CSC, error) {
+	v, z, err := s.values(f, rows*cols, cplx)
+	if err != nil {
+		return nil, err
+	}
+	return full(rows, cols, v, z), nil
+}
+
+// full returns the rows by cols matrix with the elements v or z, stored
+// by columns.
+func full(rows, cols int, v []float64, z []complex128) *sparse.CSC {
+	a := &sparse.CSC{
+		Rows: rows, Cols: cols,
+		ColPtr: make([]int, cols+1),
+		RowInd: make([]int, rows*cols),
+		Values: v, ZValues: z,
+	}
+	for j := 0; j < cols; j++ {
+		a.ColPtr[j+1] = a.ColPtr[j] + rows
+		for i := 0; i < rows; i++ {
+			a.RowInd[j*rows+i] = i
+		}
+	}
+	return a
+}
+
+// column returns line[i:j], truncated to the length of line.
+func column(line string, i, j int) string {
+	if i > len(line) {
+		return ""
+	}
+	if j > len(line) {
+		j = len(line)
+	}
+	return line[i:j]
+}
+
+func parseInts(s string, n int) ([]int, error) {
+	f := strings.Fields(s)
+	if len(f) < n {
+		return nil, fmt.Errorf("expected %d integers", n)
+	}
+	v := make([]int, n)
+	for k := range v {
+		var err error
+		if v[k], err = strconv.Atoi(f[k]); err != nil || v[k] < 0 {
+			return nil, fmt.Errorf("invalid integer %q", f[k])
+		}
+	}
+	return v, nil
+}
+
+// format is a Fortran edit descriptor for fixed width fields, such as
+// (10I8) or (1P,4E20.12), with perLine fields of the given width.
+type format struct {
+	perLine int
+	width   int
+	kind    byte
+}
+
+func parseFormat(s string) (format, error) {
+	var f format
+	t := strings.ToUpper(strings.Replace(strings.TrimSpace(s), " ", "", -1))
+	if len(t) < 2 || t[0] != '(' || t[len(t)-1] != ')' {
+		return f, fmt.Errorf("invalid format %q", s)
+	}
+	t = t[1 : len(t)-1]
+
+	// A scale factor only affects output.
+	if p := strings.IndexByte(t, 'P'); p >= 0 {
+		t = strings.TrimPrefix(t[p+1:], ",")
+	}
+	k := strings.IndexAny(t, "IEDFG")
+	if k < 0 {
+		return f, fmt.Errorf("unsupported format %q", s)
+	}
+	f.kind = t[k]
+	f.perLine = 1
+	if k > 0 {
+		n, err := strconv.Atoi(t[:k])
+		if err != nil || n < 1 {
+			return f, fmt.Errorf("unsupported format %q", s)
+		}
+		f.perLine = n
+	}
+	w := t[k+1:]
+	if d := strings.IndexByte(w, '.'); d >= 0 {
+		w = w[:d]
+	}
+	n, err := strconv.Atoi(w)
+	if err != nil || n < 1 {
+		return f, fmt.Errorf("unsupported format %q", s)
+	}
+	f.width = n
+	return f, nil
+}
+
+type scanner struct {
+	s      *bufio.Scanner
+	lineno int
+}
+
+func (s *scanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", s.lineno, fmt.Sprintf(format, args...))
+}
+
+func (s *scanner) line() (string, error) {
+	if !s.s.Scan() {
+		if err := s.s.Err(); err != nil {
+			return "", err
+		}
+		return "", io.ErrUnexpectedEOF
+	}
+	s.lineno++
+	return strings.TrimRight(s.s.Text(), "\r"), nil
+}
+
+// fields returns the next n fields, reading lines of f.perLine fields
+// of width f.width.
+func (s *scanner) fields(f format, n int, field func(k int, text string) error) error {
+	for k := 0; k < n; {
+		line, err := s.line()
+		if err != nil {
+			return err
+		}
+		for c := 0; c < f.perLine && k < n; c++ {
+			text := strings.TrimSpace(column(line, c*f.width, (c+1)*f.width))
+			if text == "" {
+				break
+			}
+			if err := field(k, text); err != nil {
+				return s.errorf("%v", err)
+			}
+			k++
+		}
+	}
+	return nil
+}
+
+// indexes reads n one-based indexes and returns them zero-based.
+func (s *scanner) indexes(f format, n int) ([]int, error) {
+	if f.kind != 'I' {
+		return nil, s.errorf("index format must be integer")
+	}
+	v := make([]int, n)
+	err := s.fields(f, n, func(k int, text string) error {
+		i, err := strconv.Atoi(text)
+		if err != nil || i < 1 {
+			return fmt.Errorf("invalid index %q", text)
+		}
+		v[k] = i - 1
+		return nil
+	})
+	return v, err
+}
+
+// values reads n real values, or n complex values stored as pairs of
+// real and imaginary parts if cplx is true.
+func (s *scanner) values(f format, n int, cplx bool) ([]float64, []complex128, error) {
+	m := n
+	if cplx {
+		m *= 2
+	}
+	v := make([]float64, m)
+	err := s.fields(f, m, func(k int, text string) error {
+		x, err := parseFloat(text)
+		if err != nil {
+			return err
+		}
+		v[k] = x
+		return nil
+	})
+	if err != nil || !cplx {
+		return v, nil, err
+	}
+	z := make([]complex128, n)
+	for k := range z {
+		z[k] = complex(v[2*k], v[2*k+1])
+	}
+	return nil, z, nil
+}
+
+// parseFloat parses a Fortran real, which may have a D exponent or an
+// exponent without a letter, such as 1.5-300.
+func parseFloat(text string) (float64, error) {
+	t := strings.Map(func(r rune) rune {
+		if r == 'D' || r == 'd' {
+			return 'E'
+		}
+		return r
+	}, text)
+	if !strings.ContainsAny(t, "Ee") {
+		if k := strings.LastIndexAny(t, "+-"); k > 0 {
+			t = t[:k] + "E" + t[k:]
+		}
+	}
+	x, err := strconv.ParseFloat(t, 64)
+	if err != nil {
+		return 0, errors.New("invalid value " + strconv.Quote(text))
+	}
+	return x, nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package hb_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rwl/lufact/sparse"
+	"github.com/rwl/lufact/sparse/hb"
+)
+
+const rua = `Test matrix                                                             TEST    
+             4             1             1             2             0
+RUA                        3             3             5             0
+(4I4)           (5I4)           (3E16.8)            
+   1   3   4   6
+   1   3   2   1   3
+  4.00000000E+00  1.00000000D+00  5.0000000-001
+  2.00000000E+00  6.00000000E+00
+`
+
+const rsa = `Symmetric                                                               SYM     
+             3             1             1             1             0
+RSA                        2             2             2             0
+(3I4)           (2I4)           (1P,2E12.4)         
+   1   3   3
+   1   2
+  2.0000E+00 -1.0000E+00
+`
+
+const rb = `Rutherford-Boeing integer                                               RBINT   
+             3             1             1             1
+iua                        2             2             3             0
+(3I3)           (3I3)           (3I5)               
+  1  3  4
+  1  2  2
+    2   -1    7
+`
+
+const cua = `Complex with rhs                                                        CRHS    
+             6             1             1             2             2
+CUA                        2             2             2             0
+(3I3)           (2I3)           (4E10.3)            (4E10.3)            
+F X                        1             0
+  1  2  3
+  1  2
+ 2.000E+00 1.000E+00 3.000E+00 0.000E+00
+ 2.000E+00 1.000E+00 3.000E+00 0.000E+00
+ 1.000E+00 0.000E+00 1.000E+00 0.000E+00
+`
+
+func TestRead(t *testing.T) {
+	f, err := hb.Read(strings.NewReader(rua))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Title != "Test matrix" || f.Key != "TEST" || f.Type != "RUA" {
+		t.Errorf("header %q %q %q", f.Title, f.Key, f.Type)
+	}
+	want := &sparse.CSC{
+		Rows: 3, Cols: 3,
+		ColPtr: []int{0, 2, 3, 5},
+		RowInd: []int{0, 2, 1, 0, 2},
+		Values: []float64{4, 1, 0.5, 2, 6},
+	}
+	if !reflect.DeepEqual(f.A, want) {
+		t.Errorf("got %+v, want %+v", f.A, want)
+	}
+
+	f, err = hb.Read(strings.NewReader(rsa))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Symmetry() != sparse.Symmetric {
+		t.Errorf("symmetry %v", f.Symmetry())
+	}
+	a, err := f.Matrix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = &sparse.CSC{
+		Rows: 2, Cols: 2,
+		ColPtr: []int{0, 2, 3},
+		RowInd: []int{0, 1, 0},
+		Values: []float64{2, -1, -1},
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("got %+v, want %+v", a, want)
+	}
+
+	f, err = hb.Read(strings.NewReader(rb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{2, -1, 7}; f.Type != "IUA" || !reflect.DeepEqual(f.A.Values, want) || f.RHS != nil {
+		t.Errorf("Rutherford-Boeing got %q %+v", f.Type, f.A)
+	}
+
+	f, err = hb.Read(strings.NewReader(cua))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []complex128{2 + 1i, 3}; !reflect.DeepEqual(f.A.ZValues, want) {
+		t.Errorf("complex got %v, want %v", f.A.ZValues, want)
+	}
+	if want := []complex128{2 + 1i, 3}; f.RHS == nil || f.RHS.Cols != 1 || !reflect.DeepEqual(f.RHS.ZValues, want) {
+		t.Errorf("right-hand side got %+v", f.RHS)
+	}
+	if want := []complex128{1, 1}; f.Guess != nil || f.Exact == nil || !reflect.DeepEqual(f.Exact.ZValues, want) {
+		t.Errorf("exact solution got %+v", f.Exact)
+	}
+
+	for _, bad := range []string{
+		"",
+		strings.Replace(rua, "RUA", "RUE", 1),
+		strings.Replace(rua, "(5I4)", "(5X4)", 1),
+		strings.Replace(rua, "   1   3   2   1   3", "   1   3   2   1   4", 1),
+		rua[:len(rua)-40],
+		strings.Replace(cua, "F X", "Q X", 1),
+		cua[:len(cua)-40],
+	} {
+		if _, err := hb.Read(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	real := &sparse.CSC{
+		Rows: 3, Cols: 3,
+		ColPtr: []int{0, 2, 3, 5},
+		RowInd: []int{0, 2, 1, 0, 2},
+		Values: []float64{4, 1e-300, -0.1, 2, 1.0 / 3},
+	}
+	lower := &sparse.CSC{
+		Rows: 2, Cols: 2,
+		ColPtr:  []int{0, 2, 3},
+		RowInd:  []int{0, 1, 1},
+		ZValues: []complex128{1, 2 - 1i, 3i},
+	}
+	pattern := &sparse.CSC{
+		Rows: 2, Cols: 3,
+		ColPtr: []int{0, 0, 1, 3},
+		RowInd: []int{1, 0, 1},
+	}
+	full := &sparse.CSC{
+		Rows: 3, Cols: 2,
+		ColPtr: []int{0, 3, 6},
+		RowInd: []int{0, 1, 2, 0, 1, 2},
+		Values: []float64{1, 2, 3, 4, 5, 0},
+	}
+	sparseRHS := &sparse.CSC{
+		Rows: 2, Cols: 2,
+		ColPtr:  []int{0, 1, 1},
+		RowInd:  []int{1},
+		ZValues: []complex128{1 - 1i},
+	}
+	guess := &sparse.CSC{
+		Rows: 2, Cols: 2,
+		ColPtr:  []int{0, 2, 4},
+		RowInd:  []int{0, 1, 0, 1},
+		ZValues: []complex128{1, 2, 3, 4i},
+	}
+	// The right-hand side has more nonzeros than the matrix, so its
+	// column pointers are wider.
+	diag := &sparse.CSC{
+		Rows: 3, Cols: 3,
+		ColPtr: []int{0, 1, 2, 3},
+		RowInd: []int{0, 1, 2},
+		Values: []float64{1, 2, 3},
+	}
+	wide := &sparse.CSC{Rows: 3, Cols: 40, ColPtr: []int{0}}
+	for j := 0; j < wide.Cols; j++ {
+		for i := 0; i < 3; i++ {
+			if j < 20 || i != j%3 {
+				wide.RowInd = append(wide.RowInd, i)
+				wide.Values = append(wide.Values, float64(j*3+i))
+			}
+		}
+		wide.ColPtr = append(wide.ColPtr, len(wide.RowInd))
+	}
+
+	for _, f := range []*hb.File{
+		{Title: "real", Key: "R1", Type: "RUA", A: real},
+		{Title: "wide rhs", Key: "W", Type: "RUA", A: diag, RHS: wide},
+		{Title: "real with rhs", Key: "R2", Type: "RUA", A: real, RHS: full, Guess: full, Exact: full},
+		{Title: "hermitian", Key: "H", Type: "CHA", A: lower, RHS: sparseRHS, Guess: guess},
+		{Title: "pattern", Key: "P", Type: "PRA", A: pattern},
+	} {
+		var buf bytes.Buffer
+		if err := hb.Write(&buf, f); err != nil {
+			t.Fatalf("%s: %v", f.Title, err)
+		}
+		for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if len(line) > 80 {
+				t.Errorf("%s: line %d has %d characters", f.Title, i+1, len(line))
+			}
+		}
+		g, err := hb.Read(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", f.Title, err)
+		}
+		if !reflect.DeepEqual(f, g) {
+			t.Errorf("%s: round trip got %+v, want %+v", f.Title, g, f)
+		}
+	}
+
+	// The type is taken from the values if it is not given.
+	var buf bytes.Buffer
+	if err := hb.Write(&buf, &hb.File{A: lower}); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := hb.Read(&buf); err != nil || g.Type != "CUA" {
+		t.Errorf("type %q: %v", g.Type, err)
+	}
+
+	for _, f := range []*hb.File{
+		{},
+		{Type: "RUA", A: lower},
+		{Type: "RUE", A: real},
+		{Type: "PSA", A: pattern},
+		{A: real, RHS: sparseRHS},
+		{A: real, Guess: full},
+		{A: lower, RHS: full},
+	} {
+		if err := hb.Write(&buf, f); err == nil {
+			t.Errorf("expected error for %+v", f)
+		}
+	}
+}
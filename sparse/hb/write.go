@@ -0,0 +1,255 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package hb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rwl/lufact/sparse"
+)
+
+// valueFormat holds 17 significant digits, so that values read back
+// exactly.
+var valueFormat = format{perLine: 3, width: 25, kind: 'E'}
+
+// Write writes f in Harwell-Boeing format. If f.Type is empty it is
+// RUA, CUA or PUA according to the values of f.A, otherwise its first
+// letter must match them. For the symmetric types f.A must hold the
+// lower triangle. The right-hand sides are written in full if every
+// element of f.RHS is stored and in compressed column form otherwise.
+func Write(w io.Writer, f *File) error {
+	a := f.A
+	if a == nil {
+		return errors.New("matrix must not be nil")
+	}
+	if err := a.Check(); err != nil {
+		return err
+	}
+	typ, err := fileType(f)
+	if err != nil {
+		return err
+	}
+	if err := checkRHS(f); err != nil {
+		return err
+	}
+	cplx := a.IsComplex()
+	nnz := a.NNZ()
+
+	// The column pointers of a compressed right-hand side are written
+	// with the same format as those of A.
+	maxptr := nnz + 1
+	if b := f.RHS; b != nil && b.NNZ()+1 > maxptr {
+		maxptr = b.NNZ() + 1
+	}
+	ptrfmt := indexFormat(maxptr)
+	indfmt := indexFormat(a.Rows)
+	valfmt := valueFormat
+	ptrcrd := lines(ptrfmt, a.Cols+1)
+	indcrd := lines(indfmt, nnz)
+	var valcrd int
+	if typ[0] != 'P' {
+		valcrd = lines(valfmt, values(nnz, cplx))
+	}
+
+	var rhstyp string
+	var rhscrd, nrhs, nrhsix int
+	b := f.RHS
+	if b != nil {
+		nrhs = b.Cols
+		rhstyp = "M"
+		rhscrd = lines(ptrfmt, nrhs+1) + lines(indfmt, b.NNZ()) + lines(valfmt, values(b.NNZ(), cplx))
+		nrhsix = b.NNZ()
+		if b.NNZ() == b.Rows*b.Cols {
+			rhstyp = "F"
+			rhscrd = lines(valfmt, values(b.NNZ(), cplx))
+			nrhsix = 0
+		}
+		rhstyp += " "
+		if f.Guess != nil {
+			rhstyp = rhstyp[:1] + "G"
+			rhscrd += lines(valfmt, values(b.Rows*nrhs, cplx))
+		}
+		if f.Exact != nil {
+			rhstyp += "X"
+			rhscrd += lines(valfmt, values(b.Rows*nrhs, cplx))
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%-72.72s%-8.8s\n", f.Title, f.Key)
+	fmt.Fprintf(bw, "%14d%14d%14d%14d%14d\n", ptrcrd+indcrd+valcrd+rhscrd, ptrcrd, indcrd, valcrd, rhscrd)
+	fmt.Fprintf(bw, "%-3s%11s%14d%14d%14d%14d\n", typ, "", a.Rows, a.Cols, nnz, 0)
+	fmt.Fprintf(bw, "%-16s%-16s", ptrfmt, indfmt)
+	if typ[0] != 'P' || b != nil {
+		fmt.Fprintf(bw, "%-20s", valfmt)
+	}
+	if b != nil {
+		fmt.Fprintf(bw, "%-20s", valfmt)
+	}
+	bw.WriteByte('\n')
+	if b != nil {
+		fmt.Fprintf(bw, "%-3s%11s%14d%14d\n", rhstyp, "", nrhs, nrhsix)
+	}
+
+	writeIndexes(bw, ptrfmt, a.ColPtr)
+	writeIndexes(bw, indfmt, a.RowInd)
+	if typ[0] != 'P' {
+		writeValues(bw, valfmt, a.Values, a.ZValues)
+	}
+	if b != nil {
+		if rhstyp[0] == 'M' {
+			writeIndexes(bw, ptrfmt, b.ColPtr)
+			writeIndexes(bw, indfmt, b.RowInd)
+			writeValues(bw, valfmt, b.Values, b.ZValues)
+		} else {
+			writeDense(bw, valfmt, b)
+		}
+		if f.Guess != nil {
+			writeDense(bw, valfmt, f.Guess)
+		}
+		if f.Exact != nil {
+			writeDense(bw, valfmt, f.Exact)
+		}
+	}
+	return bw.Flush()
+}
+
+// fileType returns the type of f, checking it against the values of
+// f.A.
+func fileType(f *File) (string, error) {
+	a := f.A
+	kind := byte('R')
+	switch {
+	case a.IsComplex():
+		kind = 'C'
+	case a.IsPattern():
+		kind = 'P'
+	}
+	if f.Type == "" {
+		return string(kind) + "UA", nil
+	}
+	typ := strings.ToUpper(f.Type)
+	if len(typ) != 3 || !strings.ContainsRune("SUHZR", rune(typ[1])) || typ[2] != 'A' {
+		return "", fmt.Errorf("unsupported matrix type %q", f.Type)
+	}
+	if typ[0] != kind && !(typ[0] == 'I' && kind == 'R') {
+		return "", fmt.Errorf("matrix type %q does not match the values", f.Type)
+	}
+	if typ[1] != 'R' && a.Rows != a.Cols {
+		return "", fmt.Errorf("matrix type %q must be square (%v by %v)", f.Type, a.Rows, a.Cols)
+	}
+	if typ[0] == 'I' {
+		// Integer values are written in a real format, which is a
+		// Harwell-Boeing real matrix.
+		typ = "R" + typ[1:]
+	}
+	return typ, nil
+}
+
+// checkRHS checks that the right-hand sides, guesses and exact
+// solutions match the matrix.
+func checkRHS(f *File) error {
+	b := f.RHS
+	if b == nil {
+		if f.Guess != nil || f.Exact != nil {
+			return errors.New("guesses and exact solutions require right-hand sides")
+		}
+		return nil
+	}
+	for _, c := range []struct {
+		name string
+		m    *sparse.CSC
+	}{{"right-hand side", b}, {"guess", f.Guess}, {"exact solution", f.Exact}} {
+		if c.m == nil {
+			continue
+		}
+		if err := c.m.Check(); err != nil {
+			return fmt.Errorf("%s: %v", c.name, err)
+		}
+		if c.m.Rows != f.A.Rows || c.m.Cols != b.Cols {
+			return fmt.Errorf("%s must be %v by %v", c.name, f.A.Rows, b.Cols)
+		}
+		if c.m.IsComplex() != f.A.IsComplex() || c.m.IsPattern() {
+			return fmt.Errorf("%s values must match the matrix", c.name)
+		}
+	}
+	return nil
+}
+
+// indexFormat returns the format of one-based indexes up to max.
+func indexFormat(max int) format {
+	width := len(strconv.Itoa(max)) + 1
+	return format{perLine: 80 / width, width: width, kind: 'I'}
+}
+
+func (f format) String() string {
+	if f.kind == 'I' {
+		return fmt.Sprintf("(%dI%d)", f.perLine, f.width)
+	}
+	return fmt.Sprintf("(1P,%dE%d.%d)", f.perLine, f.width, f.width-9)
+}
+
+// lines returns the number of lines for n fields.
+func lines(f format, n int) int {
+	return (n + f.perLine - 1) / f.perLine
+}
+
+// values returns the number of real values for n values.
+func values(n int, cplx bool) int {
+	if cplx {
+		return 2 * n
+	}
+	return n
+}
+
+// writeDense writes the elements of a by columns, including the zeros.
+func writeDense(w *bufio.Writer, f format, a *sparse.CSC) {
+	var v []float64
+	var z []complex128
+	if a.IsComplex() {
+		z = make([]complex128, a.Rows*a.Cols)
+	} else {
+		v = make([]float64, a.Rows*a.Cols)
+	}
+	for j := 0; j < a.Cols; j++ {
+		for p := a.ColPtr[j]; p < a.ColPtr[j+1]; p++ {
+			if z != nil {
+				z[j*a.Rows+a.RowInd[p]] += a.ZValues[p]
+			} else {
+				v[j*a.Rows+a.RowInd[p]] += a.Values[p]
+			}
+		}
+	}
+	writeValues(w, f, v, z)
+}
+
+// writeIndexes writes the zero-based indexes ind one-based.
+func writeIndexes(w *bufio.Writer, f format, ind []int) {
+	for k, i := range ind {
+		fmt.Fprintf(w, "%*d", f.width, i+1)
+		if (k+1)%f.perLine == 0 || k == len(ind)-1 {
+			w.WriteByte('\n')
+		}
+	}
+}
+
+// writeValues writes v, or z as pairs of real and imaginary parts.
+func writeValues(w *bufio.Writer, f format, v []float64, z []complex128) {
+	if z != nil {
+		v = make([]float64, 0, 2*len(z))
+		for _, x := range z {
+			v = append(v, real(x), imag(x))
+		}
+	}
+	for k, x := range v {
+		fmt.Fprintf(w, "%*.*E", f.width, f.width-9, x)
+		if (k+1)%f.perLine == 0 || k == len(v)-1 {
+			w.WriteByte('\n')
+		}
+	}
+}
@@ -0,0 +1,517 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+// Package mat reads and writes matrices in MATLAB Level 5 MAT-files,
+// as saved by MATLAB with the -v6 or -v7 options.
+package mat
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/rwl/lufact/sparse"
+)
+
+// Data types of the data elements.
+const (
+	miINT8       = 1
+	miUINT8      = 2
+	miINT16      = 3
+	miUINT16     = 4
+	miINT32      = 5
+	miUINT32     = 6
+	miSINGLE     = 7
+	miDOUBLE     = 9
+	miINT64      = 12
+	miUINT64     = 13
+	miMATRIX     = 14
+	miCOMPRESSED = 15
+)
+
+// Array classes, and the flags of the array flags subelement.
+const (
+	mxSPARSE = 5
+	mxDOUBLE = 6
+	mxUINT64 = 15
+
+	flagComplex = 0x0800
+	flagLogical = 0x0200
+)
+
+const headerLen = 128
+
+// Var is a named matrix.
+type Var struct {
+	Name string
+	A    *sparse.CSC
+}
+
+// Read reads the two-dimensional sparse and numeric arrays in a MAT-file.
+// Sparse arrays are returned as stored and full arrays without their
+// zero elements. Logical arrays have the values 0 and 1. Other
+// variables, such as cell arrays, structures and strings, are skipped.
+func Read(r io.Reader) ([]Var, error) {
+	var h [headerLen]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return nil, err
+	}
+	var order binary.ByteOrder
+	switch string(h[126:]) {
+	case "IM":
+		order = binary.LittleEndian
+	case "MI":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("not a Level 5 MAT-file")
+	}
+	if v := order.Uint16(h[124:]); v != 0x0100 {
+		return nil, fmt.Errorf("unsupported MAT-file version %#x", v)
+	}
+
+	var vars []Var
+	for {
+		var tag [8]byte
+		if _, err := io.ReadFull(r, tag[:]); err == io.EOF {
+			return vars, nil
+		} else if err != nil {
+			return nil, err
+		}
+		typ, n := order.Uint32(tag[:]), order.Uint32(tag[4:])
+		data, err := ioutil.ReadAll(io.LimitReader(r, int64(n)))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) != int(n) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if typ == miCOMPRESSED {
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			data, err = ioutil.ReadAll(zr)
+			if err != nil {
+				return nil, err
+			}
+			e := &elements{order: order, data: data}
+			if typ, data, err = e.next(); err != nil {
+				return nil, err
+			}
+		} else if pad := (8 - n%8) % 8; pad != 0 {
+			if _, err := io.ReadFull(r, make([]byte, pad)); err != nil {
+				return nil, err
+			}
+		}
+		if typ != miMATRIX {
+			continue
+		}
+		v, err := readMatrix(order, data)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			vars = append(vars, *v)
+		}
+	}
+}
+
+// elements reads the data elements in data.
+type elements struct {
+	order binary.ByteOrder
+	data  []byte
+}
+
+// next returns the type and data of the next element.
+func (e *elements) next() (uint32, []byte, error) {
+	if len(e.data) < 8 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	tag := e.order.Uint32(e.data)
+
+	// A small data element has the length in the upper two bytes of
+	// the tag and at most four bytes of data.
+	if n := tag >> 16; n != 0 {
+		if n > 4 {
+			return 0, nil, errors.New("invalid small data element")
+		}
+		data := e.data[4 : 4+n]
+		e.data = e.data[8:]
+		return tag & 0xffff, data, nil
+	}
+	n := uint64(e.order.Uint32(e.data[4:]))
+	if n > uint64(len(e.data)-8) {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	data := e.data[8 : 8+n]
+	if n += 8 + (8-n%8)%8; n > uint64(len(e.data)) {
+		n = uint64(len(e.data))
+	}
+	e.data = e.data[n:]
+	return tag, data, nil
+}
+
+// readMatrix reads the subelements of a miMATRIX element, and returns
+// nil if it is not a supported array.
+func readMatrix(order binary.ByteOrder, data []byte) (*Var, error) {
+	e := &elements{order: order, data: data}
+	typ, flags, err := e.next()
+	if err != nil {
+		return nil, err
+	}
+	if typ != miUINT32 || len(flags) != 8 {
+		return nil, errors.New("invalid array flags")
+	}
+	class := order.Uint32(flags) & 0xff
+	cplx := order.Uint32(flags)&flagComplex != 0
+	logical := order.Uint32(flags)&flagLogical != 0
+	if class != mxSPARSE && (class < mxDOUBLE || class > mxUINT64) {
+		return nil, nil
+	}
+
+	typ, data, err = e.next()
+	if err != nil {
+		return nil, err
+	}
+	dims, err := ints(order, typ, data)
+	if err != nil {
+		return nil, fmt.Errorf("dimensions: %v", err)
+	}
+	if len(dims) != 2 {
+		return nil, nil
+	}
+	rows, cols := dims[0], dims[1]
+	if rows < 0 || cols < 0 {
+		return nil, fmt.Errorf("invalid dimensions %v by %v", rows, cols)
+	}
+	typ, name, err := e.next()
+	if err != nil {
+		return nil, err
+	}
+	if typ != miINT8 && typ != miUINT8 {
+		return nil, errors.New("invalid array name")
+	}
+	v := &Var{Name: string(name)}
+
+	var rowind, colptr []int
+	if class == mxSPARSE {
+		if typ, data, err = e.next(); err != nil {
+			return nil, err
+		}
+		if rowind, err = ints(order, typ, data); err != nil {
+			return nil, fmt.Errorf("%s: row indexes: %v", v.Name, err)
+		}
+		if typ, data, err = e.next(); err != nil {
+			return nil, err
+		}
+		if colptr, err = ints(order, typ, data); err != nil {
+			return nil, fmt.Errorf("%s: column pointers: %v", v.Name, err)
+		}
+		if len(colptr) != cols+1 {
+			return nil, fmt.Errorf("%s: len column pointers (%v) must be %v", v.Name, len(colptr), cols+1)
+		}
+	} else if rows != 0 && cols > math.MaxInt32/rows {
+		return nil, fmt.Errorf("%s: array is too large", v.Name)
+	}
+
+	var re, im []float64
+	if len(e.data) > 0 || !logical {
+		if typ, data, err = e.next(); err != nil {
+			return nil, err
+		}
+		if re, err = floats(order, typ, data); err != nil {
+			return nil, fmt.Errorf("%s: real part: %v", v.Name, err)
+		}
+	}
+	if cplx {
+		if typ, data, err = e.next(); err != nil {
+			return nil, err
+		}
+		if im, err = floats(order, typ, data); err != nil {
+			return nil, fmt.Errorf("%s: imaginary part: %v", v.Name, err)
+		}
+		if len(im) != len(re) {
+			return nil, fmt.Errorf("%s: real and imaginary parts differ in length", v.Name)
+		}
+	}
+
+	// Gather the elements, in column order, and their values.
+	var rowOf, colOf []int
+	var values []float64
+	var zvalues []complex128
+	add := func(i, j, k int) {
+		rowOf = append(rowOf, i)
+		colOf = append(colOf, j)
+		var x float64 = 1
+		if re != nil {
+			x = re[k]
+		}
+		if cplx {
+			zvalues = append(zvalues, complex(x, im[k]))
+		} else {
+			values = append(values, x)
+		}
+	}
+	if class == mxSPARSE {
+		nnz := colptr[cols]
+		if colptr[0] != 0 || nnz > len(rowind) || (re != nil && nnz > len(re)) {
+			return nil, fmt.Errorf("%s: invalid column pointers", v.Name)
+		}
+		for j := 0; j < cols; j++ {
+			if colptr[j] > colptr[j+1] {
+				return nil, fmt.Errorf("%s: invalid column pointers", v.Name)
+			}
+		}
+		for j := 0; j < cols; j++ {
+			for p := colptr[j]; p < colptr[j+1]; p++ {
+				add(rowind[p], j, p)
+			}
+		}
+	} else {
+		if len(re) != rows*cols {
+			return nil, fmt.Errorf("%s: len data (%v) must be %v", v.Name, len(re), rows*cols)
+		}
+		for j := 0; j < cols; j++ {
+			for i := 0; i < rows; i++ {
+				k := j*rows + i
+				if re[k] != 0 || (cplx && im[k] != 0) {
+					add(i, j, k)
+				}
+			}
+		}
+	}
+	if cplx && zvalues == nil {
+		zvalues = []complex128{}
+	} else if !cplx && values == nil {
+		values = []float64{}
+	}
+	if v.A, err = sparse.FromCOO(rows, cols, rowOf, colOf, values, zvalues); err != nil {
+		return nil, fmt.Errorf("%s: %v", v.Name, err)
+	}
+	return v, nil
+}
+
+// size returns the size in bytes of the elements of a numeric type.
+func size(typ uint32) int {
+	switch typ {
+	case miINT8, miUINT8:
+		return 1
+	case miINT16, miUINT16:
+		return 2
+	case miINT32, miUINT32, miSINGLE:
+		return 4
+	case miDOUBLE, miINT64, miUINT64:
+		return 8
+	}
+	return 0
+}
+
+// ints returns the elements of an integer data element.
+func ints(order binary.ByteOrder, typ uint32, data []byte) ([]int, error) {
+	if typ == miSINGLE || typ == miDOUBLE || size(typ) == 0 {
+		return nil, fmt.Errorf("data type %d is not an integer type", typ)
+	}
+	s := size(typ)
+	v := make([]int, len(data)/s)
+	for k := range v {
+		b := data[k*s:]
+		var x int64
+		switch typ {
+		case miINT8:
+			x = int64(int8(b[0]))
+		case miUINT8:
+			x = int64(b[0])
+		case miINT16:
+			x = int64(int16(order.Uint16(b)))
+		case miUINT16:
+			x = int64(order.Uint16(b))
+		case miINT32:
+			x = int64(int32(order.Uint32(b)))
+		case miUINT32:
+			x = int64(order.Uint32(b))
+		default:
+			x = int64(order.Uint64(b))
+			if x < 0 && typ == miUINT64 {
+				return nil, errors.New("integer out of range")
+			}
+		}
+		if int64(int(x)) != x {
+			return nil, errors.New("integer out of range")
+		}
+		v[k] = int(x)
+	}
+	return v, nil
+}
+
+// floats returns the elements of a numeric data element. MATLAB may
+// store doubles with a smaller type that holds their values exactly.
+func floats(order binary.ByteOrder, typ uint32, data []byte) ([]float64, error) {
+	switch typ {
+	case miDOUBLE:
+		v := make([]float64, len(data)/8)
+		for k := range v {
+			v[k] = math.Float64frombits(order.Uint64(data[8*k:]))
+		}
+		return v, nil
+	case miSINGLE:
+		v := make([]float64, len(data)/4)
+		for k := range v {
+			v[k] = float64(math.Float32frombits(order.Uint32(data[4*k:])))
+		}
+		return v, nil
+	}
+	n, err := ints(order, typ, data)
+	if err != nil {
+		return nil, err
+	}
+	v := make([]float64, len(n))
+	for k, x := range n {
+		v[k] = float64(x)
+	}
+	return v, nil
+}
+
+// Write writes the matrices as sparse double arrays in a MAT-file,
+// compressing each with zlib if compress is true, as for the -v7
+// option of save. The names must be valid MATLAB variable names. Pattern
+// matrices are written with the value one for each element.
+func Write(w io.Writer, vars []Var, compress bool) error {
+	var h [headerLen]byte
+	text := fmt.Sprintf("MATLAB 5.0 MAT-file, Platform: %s, Created on: %s",
+		runtime.GOOS, time.Now().UTC().Format(time.ANSIC))
+	copy(h[:116], text+string(bytes.Repeat([]byte{' '}, 116)))
+	binary.LittleEndian.PutUint16(h[124:], 0x0100)
+	copy(h[126:], "IM")
+	if _, err := w.Write(h[:]); err != nil {
+		return err
+	}
+
+	for _, v := range vars {
+		if err := checkName(v.Name); err != nil {
+			return err
+		}
+		if err := v.A.Check(); err != nil {
+			return fmt.Errorf("%s: %v", v.Name, err)
+		}
+		if v.A.Rows > math.MaxInt32 || v.A.Cols > math.MaxInt32 || v.A.NNZ() > math.MaxInt32 {
+			return fmt.Errorf("%s: matrix is too large", v.Name)
+		}
+		m := matrix(v)
+		if compress {
+			var buf bytes.Buffer
+			zw := zlib.NewWriter(&buf)
+			zw.Write(m)
+			if err := zw.Close(); err != nil {
+				return err
+			}
+			m = element(miCOMPRESSED, buf.Bytes(), false)
+		}
+		if _, err := w.Write(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkName returns an error if name is not a valid variable name.
+func checkName(name string) error {
+	if name == "" || len(name) > 63 {
+		return fmt.Errorf("invalid variable name %q", name)
+	}
+	for k, c := range name {
+		letter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if !letter && (k == 0 || c != '_' && (c < '0' || c > '9')) {
+			return fmt.Errorf("invalid variable name %q", name)
+		}
+	}
+	return nil
+}
+
+// matrix returns the miMATRIX element of a sparse array.
+func matrix(v Var) []byte {
+	a := v.A
+	le := binary.LittleEndian
+	nnz := a.NNZ()
+
+	flags := make([]byte, 8)
+	f := uint32(mxSPARSE)
+	if a.IsComplex() {
+		f |= flagComplex
+	}
+	le.PutUint32(flags, f)
+	le.PutUint32(flags[4:], uint32(nnz))
+	dims := make([]byte, 8)
+	le.PutUint32(dims, uint32(a.Rows))
+	le.PutUint32(dims[4:], uint32(a.Cols))
+	int32s := func(v []int) []byte {
+		b := make([]byte, 4*len(v))
+		for k, x := range v {
+			le.PutUint32(b[4*k:], uint32(x))
+		}
+		return b
+	}
+	doubles := func(v []float64) []byte {
+		b := make([]byte, 8*len(v))
+		for k, x := range v {
+			le.PutUint64(b[8*k:], math.Float64bits(x))
+		}
+		return b
+	}
+
+	var re, im []float64
+	switch {
+	case a.IsComplex():
+		re = make([]float64, nnz)
+		im = make([]float64, nnz)
+		for k, z := range a.ZValues {
+			re[k], im[k] = real(z), imag(z)
+		}
+	case a.IsPattern():
+		re = make([]float64, nnz)
+		for k := range re {
+			re[k] = 1
+		}
+	default:
+		re = a.Values
+	}
+
+	var body []byte
+	body = append(body, element(miUINT32, flags, true)...)
+	body = append(body, element(miINT32, dims, true)...)
+	body = append(body, element(miINT8, []byte(v.Name), true)...)
+	body = append(body, element(miINT32, int32s(a.RowInd), true)...)
+	body = append(body, element(miINT32, int32s(a.ColPtr), true)...)
+	body = append(body, element(miDOUBLE, doubles(re), true)...)
+	if im != nil {
+		body = append(body, element(miDOUBLE, doubles(im), true)...)
+	}
+	return element(miMATRIX, body, true)
+}
+
+// element returns a data element, padded to a multiple of 8 bytes if
+// pad is true. Data of at most four bytes is written as a small data
+// element.
+func element(typ uint32, data []byte, pad bool) []byte {
+	le := binary.LittleEndian
+	if pad && len(data) <= 4 && typ != miMATRIX {
+		b := make([]byte, 8)
+		le.PutUint32(b, uint32(len(data))<<16|typ)
+		copy(b[4:], data)
+		return b
+	}
+	n := len(data)
+	if pad {
+		n += (8 - n%8) % 8
+	}
+	b := make([]byte, 8+n)
+	le.PutUint32(b, typ)
+	le.PutUint32(b[4:], uint32(len(data)))
+	copy(b[8:], data)
+	return b
+}
@@ -0,0 +1,178 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package mat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/rwl/lufact/sparse"
+)
+
+func TestWrite(t *testing.T) {
+	real := &sparse.CSC{
+		Rows: 3, Cols: 2,
+		ColPtr: []int{0, 2, 3},
+		RowInd: []int{0, 2, 1},
+		Values: []float64{1.0 / 3, -math.MaxFloat64, math.SmallestNonzeroFloat64},
+	}
+	cplx := &sparse.CSC{
+		Rows: 2, Cols: 2,
+		ColPtr:  []int{0, 0, 1},
+		RowInd:  []int{1},
+		ZValues: []complex128{1 + 2i},
+	}
+	empty := &sparse.CSC{
+		Rows: 4, Cols: 1,
+		ColPtr: []int{0, 0},
+		RowInd: []int{},
+		Values: []float64{},
+	}
+	vars := []Var{{"A", real}, {"Z_1", cplx}, {"E", empty}}
+
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := Write(&buf, vars, compress); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len()%8 != 0 && !compress {
+			t.Errorf("length %v is not a multiple of 8", buf.Len())
+		}
+		got, err := Read(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, vars) {
+			t.Errorf("compress %v: round trip got %+v, want %+v", compress, got, vars)
+		}
+	}
+
+	// Pattern matrices are written with ones.
+	pattern := &sparse.CSC{Rows: 1, Cols: 2, ColPtr: []int{0, 1, 1}, RowInd: []int{0}}
+	var buf bytes.Buffer
+	if err := Write(&buf, []Var{{"P", pattern}}, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].A.Values, []float64{1}) {
+		t.Errorf("pattern got %+v", got)
+	}
+
+	for _, name := range []string{"", "1A", "a-b", "_a"} {
+		if err := Write(&buf, []Var{{name, real}}, false); err == nil {
+			t.Errorf("expected error for name %q", name)
+		}
+	}
+}
+
+// testFile returns a MAT-file holding the given elements.
+func testFile(order binary.ByteOrder, elems ...[]byte) *bytes.Reader {
+	h := make([]byte, headerLen)
+	copy(h, "MATLAB 5.0 MAT-file")
+	order.PutUint16(h[124:], 0x0100)
+	order.PutUint16(h[126:], 'M'<<8|'I')
+	return bytes.NewReader(append(h, bytes.Join(elems, nil)...))
+}
+
+// testElement returns a data element in the given byte order.
+func testElement(order binary.ByteOrder, typ uint32, v interface{}) []byte {
+	var data bytes.Buffer
+	if b, ok := v.([]byte); ok {
+		data.Write(b)
+	} else {
+		binary.Write(&data, order, v)
+	}
+	b := make([]byte, 8, 8+data.Len()+7)
+	order.PutUint32(b, typ)
+	order.PutUint32(b[4:], uint32(data.Len()))
+	b = append(b, data.Bytes()...)
+	return append(b, make([]byte, (8-len(b)%8)%8)...)
+}
+
+func TestRead(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		e := func(typ uint32, v interface{}) []byte { return testElement(order, typ, v) }
+		matrix := func(sub ...[]byte) []byte { return e(miMATRIX, bytes.Join(sub, nil)) }
+
+		f := testFile(order,
+			// A full double matrix [1 0; 0 2.5].
+			matrix(
+				e(miUINT32, []uint32{mxDOUBLE, 0}),
+				e(miINT32, []int32{2, 2}),
+				e(miINT8, []byte("D")),
+				e(miDOUBLE, []float64{1, 0, 0, 2.5}),
+			),
+			// A character array, which is skipped.
+			matrix(
+				e(miUINT32, []uint32{4, 0}),
+				e(miINT32, []int32{1, 2}),
+				e(miINT8, []byte("s")),
+				e(miUINT16, []uint16{'h', 'i'}),
+			),
+			// A complex sparse matrix with its values stored as
+			// integers, and an nzmax exceeding the elements.
+			matrix(
+				e(miUINT32, []uint32{mxSPARSE | flagComplex, 3}),
+				e(miINT32, []int32{3, 2}),
+				e(miINT8, []byte("S")),
+				e(miINT32, []int32{2, 0, 0}),
+				e(miINT32, []int32{0, 1, 2}),
+				e(miUINT8, []uint8{7, 3}),
+				e(miINT16, []int16{-1, 0}),
+			),
+		)
+		vars, err := Read(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Var{
+			{"D", &sparse.CSC{Rows: 2, Cols: 2, ColPtr: []int{0, 1, 2}, RowInd: []int{0, 1}, Values: []float64{1, 2.5}}},
+			{"S", &sparse.CSC{Rows: 3, Cols: 2, ColPtr: []int{0, 1, 2}, RowInd: []int{2, 0}, ZValues: []complex128{7 - 1i, 3}}},
+		}
+		if !reflect.DeepEqual(vars, want) {
+			t.Errorf("%v: got %+v, want %+v", order, vars, want)
+		}
+
+		for name, bad := range map[string][]byte{
+			"column pointers": matrix(
+				e(miUINT32, []uint32{mxSPARSE, 1}),
+				e(miINT32, []int32{2, 2}),
+				e(miINT8, []byte("S")),
+				e(miINT32, []int32{0}),
+				e(miINT32, []int32{0, 2, 1}),
+				e(miDOUBLE, []float64{1}),
+			),
+			"row index": matrix(
+				e(miUINT32, []uint32{mxSPARSE, 1}),
+				e(miINT32, []int32{2, 1}),
+				e(miINT8, []byte("S")),
+				e(miINT32, []int32{2}),
+				e(miINT32, []int32{0, 1}),
+				e(miDOUBLE, []float64{1}),
+			),
+			"full length": matrix(
+				e(miUINT32, []uint32{mxDOUBLE, 0}),
+				e(miINT32, []int32{2, 2}),
+				e(miINT8, []byte("D")),
+				e(miDOUBLE, []float64{1, 2, 3}),
+			),
+			"truncated": matrix(
+				e(miUINT32, []uint32{mxDOUBLE, 0}),
+			)[:12],
+		} {
+			if _, err := Read(testFile(order, bad)); err == nil {
+				t.Errorf("%v: expected error for %s", order, name)
+			}
+		}
+	}
+
+	if _, err := Read(bytes.NewReader(make([]byte, headerLen))); err == nil {
+		t.Errorf("expected error for invalid header")
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+// Package mm reads and writes matrices in the Matrix Market exchange
+// format.
+package mm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rwl/lufact/sparse"
+)
+
+const banner = "%%MatrixMarket"
+
+type header struct {
+	format   string // coordinate or array
+	field    string // real, integer, complex or pattern
+	symmetry sparse.Symmetry
+}
+
+// Read reads a matrix in coordinate or array format. Real and integer
+// matrices are returned with Values, complex matrices with ZValues and
+// pattern matrices with neither. Matrices stored as symmetric,
+// skew-symmetric or Hermitian are expanded to the full matrix.
+func Read(r io.Reader) (*sparse.CSC, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	h, err := parseHeader(s.Text())
+	if err != nil {
+		return nil, err
+	}
+
+	line := 1
+	next := func() ([]string, error) {
+		for s.Scan() {
+			line++
+			text := strings.TrimSpace(s.Text())
+			if text == "" || text[0] == '%' {
+				continue
+			}
+			return strings.Fields(text), nil
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.ErrUnexpectedEOF
+	}
+	fail := func(err error) error {
+		return fmt.Errorf("line %d: %v", line, err)
+	}
+
+	size, err := next()
+	if err != nil {
+		return nil, err
+	}
+	var dims []int
+	for _, f := range size {
+		d, err := strconv.Atoi(f)
+		if err != nil || d < 0 {
+			return nil, fail(fmt.Errorf("invalid size %q", f))
+		}
+		dims = append(dims, d)
+	}
+	want := 3
+	if h.format == "array" {
+		want = 2
+	}
+	if len(dims) != want {
+		return nil, fail(errors.New("invalid size line"))
+	}
+	rows, cols := dims[0], dims[1]
+
+	var (
+		rowind, colind []int
+		values         []float64
+		zvalues        []complex128
+	)
+	switch h.field {
+	case "real", "integer":
+		values = []float64{}
+	case "complex":
+		zvalues = []complex128{}
+	}
+	add := func(i, j int, f []string) error {
+		switch h.field {
+		case "real", "integer":
+			if len(f) != 1 {
+				return errors.New("expected one value")
+			}
+			v, err := strconv.ParseFloat(f[0], 64)
+			if err != nil {
+				return err
+			}
+			values = append(values, v)
+		case "complex":
+			if len(f) != 2 {
+				return errors.New("expected two values")
+			}
+			re, err := strconv.ParseFloat(f[0], 64)
+			if err != nil {
+				return err
+			}
+			im, err := strconv.ParseFloat(f[1], 64)
+			if err != nil {
+				return err
+			}
+			zvalues = append(zvalues, complex(re, im))
+		case "pattern":
+			if len(f) != 0 {
+				return errors.New("unexpected value for pattern matrix")
+			}
+		}
+		rowind = append(rowind, i)
+		colind = append(colind, j)
+		return nil
+	}
+
+	if h.format == "coordinate" {
+		for k := 0; k < dims[2]; k++ {
+			f, err := next()
+			if err != nil {
+				return nil, err
+			}
+			if len(f) < 2 {
+				return nil, fail(errors.New("expected row and column indexes"))
+			}
+			i, err1 := strconv.Atoi(f[0])
+			j, err2 := strconv.Atoi(f[1])
+			if err1 != nil || err2 != nil || i < 1 || i > rows || j < 1 || j > cols {
+				return nil, fail(fmt.Errorf("invalid indexes %q %q", f[0], f[1]))
+			}
+			if err := add(i-1, j-1, f[2:]); err != nil {
+				return nil, fail(err)
+			}
+		}
+	} else {
+		// Arrays are stored by columns, with only the lower triangle
+		// of symmetric matrices, excluding the diagonal if
+		// skew-symmetric.
+		for j := 0; j < cols; j++ {
+			i0 := 0
+			switch h.symmetry {
+			case sparse.Symmetric, sparse.Hermitian:
+				i0 = j
+			case sparse.SkewSymmetric:
+				i0 = j + 1
+			}
+			for i := i0; i < rows; i++ {
+				f, err := next()
+				if err != nil {
+					return nil, err
+				}
+				if err := add(i, j, f); err != nil {
+					return nil, fail(err)
+				}
+			}
+		}
+	}
+
+	a, err := sparse.FromCOO(rows, cols, rowind, colind, values, zvalues)
+	if err != nil {
+		return nil, err
+	}
+	return a.Expand(h.symmetry)
+}
+
+func parseHeader(line string) (*header, error) {
+	f := strings.Fields(strings.ToLower(line))
+	if len(f) != 5 || f[0] != strings.ToLower(banner) || f[1] != "matrix" {
+		return nil, errors.New("not a Matrix Market matrix")
+	}
+	h := &header{format: f[2], field: f[3]}
+	switch h.format {
+	case "coordinate", "array":
+	default:
+		return nil, fmt.Errorf("unsupported format %q", f[2])
+	}
+	switch h.field {
+	case "real", "double", "integer":
+		h.field = "real"
+	case "complex":
+	case "pattern":
+		if h.format == "array" {
+			return nil, errors.New("array matrix must not be pattern")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported field %q", f[3])
+	}
+	switch f[4] {
+	case "general":
+		h.symmetry = sparse.General
+	case "symmetric":
+		h.symmetry = sparse.Symmetric
+	case "skew-symmetric":
+		h.symmetry = sparse.SkewSymmetric
+	case "hermitian":
+		if h.field != "complex" {
+			return nil, errors.New("hermitian matrix must be complex")
+		}
+		h.symmetry = sparse.Hermitian
+	default:
+		return nil, fmt.Errorf("unsupported symmetry %q", f[4])
+	}
+	return h, nil
+}
+
+// Write writes a in general coordinate format.
+func Write(w io.Writer, a *sparse.CSC) error {
+	if err := a.Check(); err != nil {
+		return err
+	}
+	field := "real"
+	switch {
+	case a.IsComplex():
+		field = "complex"
+	case a.IsPattern():
+		field = "pattern"
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s matrix coordinate %s general\n", banner, field)
+	fmt.Fprintf(bw, "%d %d %d\n", a.Rows, a.Cols, a.NNZ())
+	for j := 0; j < a.Cols; j++ {
+		for p := a.ColPtr[j]; p < a.ColPtr[j+1]; p++ {
+			fmt.Fprintf(bw, "%d %d", a.RowInd[p]+1, j+1)
+			switch {
+			case a.Values != nil:
+				fmt.Fprintf(bw, " %s", format(a.Values[p]))
+			case a.ZValues != nil:
+				fmt.Fprintf(bw, " %s %s", format(real(a.ZValues[p])), format(imag(a.ZValues[p])))
+			}
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteArray writes the rows by cols matrix with the elements x, stored
+// by columns, in array format.
+func WriteArray(w io.Writer, rows, cols int, x []float64) error {
+	if rows < 0 || cols < 0 || len(x) != rows*cols {
+		return fmt.Errorf("len x (%v) must be rows*cols (%v)", len(x), rows*cols)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s matrix array real general\n%d %d\n", banner, rows, cols)
+	for _, v := range x {
+		fmt.Fprintln(bw, format(v))
+	}
+	return bw.Flush()
+}
+
+// WriteZArray writes the rows by cols complex matrix with the elements
+// x, stored by columns, in array format.
+func WriteZArray(w io.Writer, rows, cols int, x []complex128) error {
+	if rows < 0 || cols < 0 || len(x) != rows*cols {
+		return fmt.Errorf("len x (%v) must be rows*cols (%v)", len(x), rows*cols)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s matrix array complex general\n%d %d\n", banner, rows, cols)
+	for _, v := range x {
+		fmt.Fprintln(bw, format(real(v)), format(imag(v)))
+	}
+	return bw.Flush()
+}
+
+// format returns the shortest representation of v that reads back
+// exactly.
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
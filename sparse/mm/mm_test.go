@@ -0,0 +1,109 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package mm_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rwl/lufact/sparse"
+	"github.com/rwl/lufact/sparse/mm"
+)
+
+func TestRead(t *testing.T) {
+	a, err := mm.Read(strings.NewReader(`%%MatrixMarket matrix coordinate real symmetric
+% A comment.
+3 3 4
+1 1 4
+3 1 -1.5
+2 2 5e-1
+3 3 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &sparse.CSC{
+		Rows: 3, Cols: 3,
+		ColPtr: []int{0, 2, 3, 5},
+		RowInd: []int{0, 2, 1, 0, 2},
+		Values: []float64{4, -1.5, 0.5, -1.5, 2},
+	}
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("got %+v, want %+v", a, want)
+	}
+
+	a, err = mm.Read(strings.NewReader(`%%MatrixMarket matrix array complex general
+2 1
+1 2
+0 -1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []complex128{1 + 2i, -1i}; !reflect.DeepEqual(a.ZValues, want) {
+		t.Errorf("array got %v, want %v", a.ZValues, want)
+	}
+
+	for _, bad := range []string{
+		"",
+		"%%MatrixMarket matrix coordinate real general\n2 2 1\n3 1 1\n",
+		"%%MatrixMarket matrix coordinate real general\n2 2 2\n1 1 1\n",
+		"%%MatrixMarket matrix coordinate real hermitian\n2 2 0\n",
+		"%%MatrixMarket vector coordinate real general\n2 2 0\n",
+	} {
+		if _, err := mm.Read(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	for _, a := range []*sparse.CSC{
+		{
+			Rows: 2, Cols: 3,
+			ColPtr: []int{0, 1, 1, 3},
+			RowInd: []int{1, 0, 1},
+			Values: []float64{0.1, -2, 1e-300},
+		},
+		{
+			Rows: 2, Cols: 2,
+			ColPtr:  []int{0, 1, 2},
+			RowInd:  []int{0, 1},
+			ZValues: []complex128{1 + 1e-17i, -3i},
+		},
+		{
+			Rows: 2, Cols: 2,
+			ColPtr: []int{0, 0, 1},
+			RowInd: []int{0},
+		},
+	} {
+		var buf bytes.Buffer
+		if err := mm.Write(&buf, a); err != nil {
+			t.Fatal(err)
+		}
+		b, err := mm.Read(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("round trip got %+v, want %+v", b, a)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := mm.WriteArray(&buf, 2, 2, []float64{1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := mm.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{1, 2, 3, 4}; b.Rows != 2 || b.Cols != 2 || !reflect.DeepEqual(b.Values, want) {
+		t.Errorf("array got %+v", b)
+	}
+	if err := mm.WriteArray(&buf, 2, 2, []float64{1}); err == nil {
+		t.Errorf("expected error for short array")
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package npz
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const npyMagic = "\x93NUMPY"
+
+// array is a NumPy array read from a .npy file.
+type array struct {
+	order binary.ByteOrder
+	kind  byte // b, i, u, f, c, S or U
+	size  int  // bytes per element
+	shape []int
+	data  []byte
+}
+
+// readArray reads a .npy file of at most max bytes.
+func readArray(r io.Reader, max uint64) (*array, error) {
+	var pre [12]byte
+	if _, err := io.ReadFull(r, pre[:8]); err != nil {
+		return nil, err
+	}
+	if string(pre[:6]) != npyMagic {
+		return nil, errors.New("not a NumPy array")
+	}
+	// The header length is 2 bytes in version 1 and 4 bytes after.
+	var hlen uint64
+	switch pre[6] {
+	case 1:
+		if _, err := io.ReadFull(r, pre[8:10]); err != nil {
+			return nil, err
+		}
+		hlen = uint64(binary.LittleEndian.Uint16(pre[8:]))
+	case 2, 3:
+		if _, err := io.ReadFull(r, pre[8:12]); err != nil {
+			return nil, err
+		}
+		hlen = uint64(binary.LittleEndian.Uint32(pre[8:]))
+	default:
+		return nil, fmt.Errorf("unsupported version %d.%d", pre[6], pre[7])
+	}
+	if hlen > max {
+		return nil, errors.New("header exceeds file size")
+	}
+	header := make([]byte, hlen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	a, err := parseHeader(string(header))
+	if err != nil {
+		return nil, err
+	}
+
+	n := uint64(a.size)
+	for _, d := range a.shape {
+		if d != 0 && n > max/uint64(d) {
+			return nil, errors.New("array exceeds file size")
+		}
+		n *= uint64(d)
+	}
+	if n > max {
+		return nil, errors.New("array exceeds file size")
+	}
+	a.data = make([]byte, n)
+	if _, err := io.ReadFull(r, a.data); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// parseHeader parses the Python dictionary literal of a .npy header,
+// such as {'descr': '<f8', 'fortran_order': False, 'shape': (3,), }.
+func parseHeader(h string) (*array, error) {
+	h = strings.TrimSpace(h)
+	if len(h) < 2 || h[0] != '{' || h[len(h)-1] != '}' {
+		return nil, fmt.Errorf("invalid header %q", h)
+	}
+	a := &array{}
+	var descr, shape string
+	var fortran bool
+	for _, key := range []string{"descr", "fortran_order", "shape"} {
+		k := strings.Index(h, "'"+key+"'")
+		if k < 0 {
+			return nil, fmt.Errorf("header has no %s", key)
+		}
+		v := strings.TrimSpace(h[k+len(key)+2:])
+		if !strings.HasPrefix(v, ":") {
+			return nil, fmt.Errorf("invalid header %q", h)
+		}
+		v = strings.TrimSpace(v[1:])
+		if v == "" {
+			return nil, fmt.Errorf("invalid header %q", h)
+		}
+		switch key {
+		case "descr":
+			end := strings.IndexByte(v[1:], v[0])
+			if (v[0] != '\'' && v[0] != '"') || end < 0 {
+				return nil, fmt.Errorf("unsupported descr in header %q", h)
+			}
+			descr = v[1 : end+1]
+		case "fortran_order":
+			fortran = strings.HasPrefix(v, "True")
+		case "shape":
+			end := strings.IndexByte(v, ')')
+			if v[0] != '(' || end < 0 {
+				return nil, fmt.Errorf("invalid shape in header %q", h)
+			}
+			shape = v[1:end]
+		}
+	}
+
+	if len(descr) < 3 {
+		return nil, fmt.Errorf("unsupported descr %q", descr)
+	}
+	switch descr[0] {
+	case '<', '|', '=':
+		a.order = binary.LittleEndian
+	case '>':
+		a.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("unsupported descr %q", descr)
+	}
+	a.kind = descr[1]
+	size, err := strconv.Atoi(descr[2:])
+	if err != nil || size < 1 {
+		return nil, fmt.Errorf("unsupported descr %q", descr)
+	}
+	a.size = size
+	switch {
+	case a.kind == 'b' && size == 1:
+	case (a.kind == 'i' || a.kind == 'u') && (size == 1 || size == 2 || size == 4 || size == 8):
+	case a.kind == 'f' && (size == 4 || size == 8):
+	case a.kind == 'c' && (size == 8 || size == 16):
+	case a.kind == 'S':
+	case a.kind == 'U':
+		if size > int(^uint(0)>>1)/4 {
+			return nil, fmt.Errorf("unsupported descr %q", descr)
+		}
+		a.size *= 4
+	default:
+		return nil, fmt.Errorf("unsupported descr %q", descr)
+	}
+
+	for _, f := range strings.Split(shape, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		d, err := strconv.Atoi(strings.TrimSuffix(f, "L"))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid shape (%s)", shape)
+		}
+		a.shape = append(a.shape, d)
+	}
+	if fortran && len(a.shape) > 1 {
+		return nil, errors.New("unsupported Fortran order array")
+	}
+	return a, nil
+}
+
+// len returns the number of elements of a one-dimensional array.
+func (a *array) len() (int, error) {
+	if len(a.shape) != 1 {
+		return 0, fmt.Errorf("array has %d dimensions, expected 1", len(a.shape))
+	}
+	return a.shape[0], nil
+}
+
+// ints returns the elements of an integer array.
+func (a *array) ints() ([]int, error) {
+	n, err := a.len()
+	if err != nil {
+		return nil, err
+	}
+	if a.kind != 'i' && a.kind != 'u' {
+		return nil, fmt.Errorf("array of kind %q, expected integers", a.kind)
+	}
+	v := make([]int, n)
+	for k := range v {
+		x, err := a.integer(k)
+		if err != nil {
+			return nil, err
+		}
+		v[k] = x
+	}
+	return v, nil
+}
+
+func (a *array) integer(k int) (int, error) {
+	b := a.data[k*a.size:]
+	var x int64
+	switch {
+	case a.size == 1 && a.kind == 'i':
+		x = int64(int8(b[0]))
+	case a.size == 1:
+		x = int64(b[0])
+	case a.size == 2 && a.kind == 'i':
+		x = int64(int16(a.order.Uint16(b)))
+	case a.size == 2:
+		x = int64(a.order.Uint16(b))
+	case a.size == 4 && a.kind == 'i':
+		x = int64(int32(a.order.Uint32(b)))
+	case a.size == 4:
+		x = int64(a.order.Uint32(b))
+	default:
+		u := a.order.Uint64(b)
+		if a.kind == 'u' && u > math.MaxInt64 {
+			return 0, fmt.Errorf("integer %v out of range", u)
+		}
+		x = int64(u)
+	}
+	if int64(int(x)) != x {
+		return 0, fmt.Errorf("integer %v out of range", x)
+	}
+	return int(x), nil
+}
+
+// values returns the elements of a numeric array, as complex values if
+// the array is complex.
+func (a *array) values() ([]float64, []complex128, error) {
+	n, err := a.len()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch a.kind {
+	case 'c':
+		z := make([]complex128, n)
+		for k := range z {
+			b := a.data[k*a.size:]
+			if a.size == 8 {
+				z[k] = complex(float64(math.Float32frombits(a.order.Uint32(b))), float64(math.Float32frombits(a.order.Uint32(b[4:]))))
+			} else {
+				z[k] = complex(math.Float64frombits(a.order.Uint64(b)), math.Float64frombits(a.order.Uint64(b[8:])))
+			}
+		}
+		return nil, z, nil
+	case 'f':
+		v := make([]float64, n)
+		for k := range v {
+			b := a.data[k*a.size:]
+			if a.size == 4 {
+				v[k] = float64(math.Float32frombits(a.order.Uint32(b)))
+			} else {
+				v[k] = math.Float64frombits(a.order.Uint64(b))
+			}
+		}
+		return v, nil, nil
+	case 'b', 'i', 'u':
+		v := make([]float64, n)
+		for k := range v {
+			if a.kind == 'b' {
+				if a.data[k] != 0 {
+					v[k] = 1
+				}
+				continue
+			}
+			x, err := a.integer(k)
+			if err != nil {
+				return nil, nil, err
+			}
+			v[k] = float64(x)
+		}
+		return v, nil, nil
+	}
+	return nil, nil, fmt.Errorf("array of kind %q, expected numbers", a.kind)
+}
+
+// str returns the value of a string scalar.
+func (a *array) str() (string, error) {
+	if len(a.shape) != 0 {
+		return "", errors.New("expected a string scalar")
+	}
+	switch a.kind {
+	case 'S':
+		return string(bytes.TrimRight(a.data, "\x00")), nil
+	case 'U':
+		var s []rune
+		for k := 0; k < len(a.data); k += 4 {
+			r := rune(a.order.Uint32(a.data[k:]))
+			if r == 0 {
+				break
+			}
+			if !utf8.ValidRune(r) {
+				return "", errors.New("invalid string")
+			}
+			s = append(s, r)
+		}
+		return string(s), nil
+	}
+	return "", fmt.Errorf("array of kind %q, expected a string", a.kind)
+}
+
+// writeArray writes a .npy file with the given descr and shape, which
+// is empty for a scalar. The data must be little-endian.
+func writeArray(w io.Writer, descr string, shape []int, data []byte) error {
+	dims := make([]string, len(shape))
+	for k, d := range shape {
+		dims[k] = strconv.Itoa(d) + ","
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, strings.Join(dims, " "))
+
+	// The header is padded with spaces and a newline so that the data
+	// is aligned to 64 bytes.
+	total := len(npyMagic) + 4 + len(header) + 1
+	header += strings.Repeat(" ", (64-total%64)%64) + "\n"
+
+	var pre [10]byte
+	copy(pre[:], npyMagic)
+	pre[6] = 1
+	binary.LittleEndian.PutUint16(pre[8:], uint16(len(header)))
+	if _, err := w.Write(pre[:]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
@@ -0,0 +1,257 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+// Package npz reads and writes sparse matrices in the .npz format of
+// scipy.sparse.save_npz and load_npz, a zip archive of NumPy arrays.
+package npz
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/rwl/lufact/sparse"
+)
+
+// Read reads a matrix in CSC, CSR or COO format from the .npz archive
+// r of the given size. The rows of each column are sorted and duplicate
+// elements are summed. Boolean, integer and real data are returned with
+// Values and complex data with ZValues.
+func Read(r io.ReaderAt, size int64) (*sparse.CSC, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	arrays := make(map[string]*array)
+	for _, f := range zr.File {
+		name := strings.TrimSuffix(f.Name, ".npy")
+		switch name {
+		case "format", "shape", "data", "indices", "indptr", "row", "col":
+		default:
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		a, err := readArray(rc, f.UncompressedSize64)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		arrays[name] = a
+	}
+	get := func(name string) (*array, error) {
+		a, ok := arrays[name]
+		if !ok {
+			return nil, fmt.Errorf("archive has no %s array", name)
+		}
+		return a, nil
+	}
+
+	a, err := get("format")
+	if err != nil {
+		return nil, err
+	}
+	format, err := a.str()
+	if err != nil {
+		return nil, fmt.Errorf("format: %v", err)
+	}
+	if a, err = get("shape"); err != nil {
+		return nil, err
+	}
+	shape, err := a.ints()
+	if err != nil || len(shape) != 2 || shape[0] < 0 || shape[1] < 0 {
+		return nil, errors.New("shape must be two dimensions")
+	}
+	rows, cols := shape[0], shape[1]
+	if a, err = get("data"); err != nil {
+		return nil, err
+	}
+	values, zvalues, err := a.values()
+	if err != nil {
+		return nil, fmt.Errorf("data: %v", err)
+	}
+	nnz := len(values) + len(zvalues)
+
+	var rowind, colind []int
+	switch format {
+	case "coo":
+		if rowind, err = index(arrays, "row", nnz); err != nil {
+			return nil, err
+		}
+		if colind, err = index(arrays, "col", nnz); err != nil {
+			return nil, err
+		}
+	case "csc", "csr":
+		ind, err := index(arrays, "indices", nnz)
+		if err != nil {
+			return nil, err
+		}
+		n := cols
+		if format == "csr" {
+			n = rows
+		}
+		ptr, err := index(arrays, "indptr", n+1)
+		if err != nil {
+			return nil, err
+		}
+		major := expand(ptr, nnz)
+		if major == nil {
+			return nil, errors.New("indptr is inconsistent with the data")
+		}
+		rowind, colind = ind, major
+		if format == "csr" {
+			rowind, colind = major, ind
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	return sparse.FromCOO(rows, cols, rowind, colind, values, zvalues)
+}
+
+// index returns the elements of the named integer array, which must
+// have n elements.
+func index(arrays map[string]*array, name string, n int) ([]int, error) {
+	a, ok := arrays[name]
+	if !ok {
+		return nil, fmt.Errorf("archive has no %s array", name)
+	}
+	v, err := a.ints()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(v) != n {
+		return nil, fmt.Errorf("len %s (%v) must be %v", name, len(v), n)
+	}
+	return v, nil
+}
+
+// expand returns the major index of each of the nnz elements given the
+// pointers of a compressed form, or nil if they are not monotone from
+// zero to nnz.
+func expand(ptr []int, nnz int) []int {
+	if ptr[0] != 0 || ptr[len(ptr)-1] != nnz {
+		return nil
+	}
+	for j := 0; j+1 < len(ptr); j++ {
+		if ptr[j] > ptr[j+1] {
+			return nil
+		}
+	}
+	ind := make([]int, nnz)
+	for j := 0; j+1 < len(ptr); j++ {
+		for p := ptr[j]; p < ptr[j+1]; p++ {
+			ind[p] = j
+		}
+	}
+	return ind
+}
+
+// ReadFile reads the matrix in the named .npz file.
+func ReadFile(name string) (*sparse.CSC, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Read(f, fi.Size())
+}
+
+// Write writes a in CSC format as a compressed .npz archive that
+// scipy.sparse.load_npz reads as a csc_matrix. The indexes are written
+// as int32 if they fit and as int64 otherwise. Pattern matrices are not
+// supported.
+func Write(w io.Writer, a *sparse.CSC) error {
+	if err := a.Check(); err != nil {
+		return err
+	}
+	if a.IsPattern() {
+		return errors.New("pattern matrix has no values")
+	}
+	nnz := a.NNZ()
+
+	wide := nnz > math.MaxInt32 || a.Rows > math.MaxInt32
+	ints := func(v []int) (string, []byte) {
+		if !wide {
+			b := make([]byte, 4*len(v))
+			for k, x := range v {
+				binary.LittleEndian.PutUint32(b[4*k:], uint32(x))
+			}
+			return "<i4", b
+		}
+		b := make([]byte, 8*len(v))
+		for k, x := range v {
+			binary.LittleEndian.PutUint64(b[8*k:], uint64(x))
+		}
+		return "<i8", b
+	}
+
+	var descr string
+	var data []byte
+	if a.IsComplex() {
+		descr = "<c16"
+		data = make([]byte, 16*nnz)
+		for k, z := range a.ZValues {
+			binary.LittleEndian.PutUint64(data[16*k:], math.Float64bits(real(z)))
+			binary.LittleEndian.PutUint64(data[16*k+8:], math.Float64bits(imag(z)))
+		}
+	} else {
+		descr = "<f8"
+		data = make([]byte, 8*nnz)
+		for k, x := range a.Values {
+			binary.LittleEndian.PutUint64(data[8*k:], math.Float64bits(x))
+		}
+	}
+	shape := make([]byte, 16)
+	binary.LittleEndian.PutUint64(shape, uint64(a.Rows))
+	binary.LittleEndian.PutUint64(shape[8:], uint64(a.Cols))
+
+	zw := zip.NewWriter(w)
+	add := func(name, descr string, dims []int, data []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+		return writeArray(f, descr, dims, data)
+	}
+	indDescr, ind := ints(a.RowInd)
+	ptrDescr, ptr := ints(a.ColPtr)
+	for _, arr := range []struct {
+		name, descr string
+		dims        []int
+		data        []byte
+	}{
+		{"indices", indDescr, []int{nnz}, ind},
+		{"indptr", ptrDescr, []int{a.Cols + 1}, ptr},
+		{"format", "|S3", nil, []byte("csc")},
+		{"shape", "<i8", []int{2}, shape},
+		{"data", descr, []int{nnz}, data},
+	} {
+		if err := add(arr.name, arr.descr, arr.dims, arr.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// WriteFile writes a to the named .npz file.
+func WriteFile(name string, a *sparse.CSC) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, a); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,218 @@
+// Copyright 2018 Richard Lincoln. All rights reserved.
+
+package npz
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/rwl/lufact/sparse"
+)
+
+// npzFile holds the arrays of an archive by name.
+type npzFile map[string]struct {
+	descr string
+	shape []int
+	data  []byte
+}
+
+func (f npzFile) bytes(t *testing.T) *bytes.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, a := range f {
+		w, err := zw.Create(name + ".npy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeArray(w, a.descr, a.shape, a.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func encode(order binary.ByteOrder, v interface{}) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, order, v)
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	le, be := binary.LittleEndian, binary.BigEndian
+
+	// The 2 by 3 matrix
+	//	[1 0 2]
+	//	[0 3 4]
+	want := &sparse.CSC{
+		Rows: 2, Cols: 3,
+		ColPtr: []int{0, 1, 2, 4},
+		RowInd: []int{0, 1, 0, 1},
+		Values: []float64{1, 3, 2, 4},
+	}
+	shape := encode(le, []int64{2, 3})
+	for _, test := range []struct {
+		name string
+		file npzFile
+	}{
+		{"csc", npzFile{
+			"format":  {"|S3", nil, []byte("csc")},
+			"shape":   {"<i8", []int{2}, shape},
+			"indptr":  {"<i4", []int{4}, encode(le, []int32{0, 1, 2, 4})},
+			"indices": {"<i4", []int{4}, encode(le, []int32{0, 1, 0, 1})},
+			"data":    {"<f8", []int{4}, encode(le, []float64{1, 3, 2, 4})},
+		}},
+		{"csr unsorted", npzFile{
+			"format":    {"<U3", nil, encode(le, []int32{'c', 's', 'r'})},
+			"shape":     {"<i8", []int{2}, shape},
+			"indptr":    {"<i8", []int{3}, encode(le, []int64{0, 2, 4})},
+			"indices":   {"<i8", []int{4}, encode(le, []int64{2, 0, 2, 1})},
+			"data":      {"<f4", []int{4}, encode(le, []float32{2, 1, 4, 3})},
+			"_is_array": {"|b1", nil, []byte{1}},
+		}},
+		{"coo duplicates", npzFile{
+			"format": {"|S3", nil, []byte("coo")},
+			"shape":  {">i8", []int{2}, encode(be, []int64{2, 3})},
+			"row":    {">i4", []int{5}, encode(be, []int32{1, 0, 0, 1, 1})},
+			"col":    {">i4", []int{5}, encode(be, []int32{2, 0, 2, 1, 2})},
+			"data":   {">i8", []int{5}, encode(be, []int64{3, 1, 2, 3, 1})},
+		}},
+	} {
+		r := test.file.bytes(t)
+		a, err := Read(r, int64(r.Len()))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(a, want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, a, want)
+		}
+	}
+
+	z := npzFile{
+		"format":  {"|S3", nil, []byte("csc")},
+		"shape":   {"<i8", []int{2}, encode(le, []int64{1, 1})},
+		"indptr":  {"<i4", []int{2}, encode(le, []int32{0, 1})},
+		"indices": {"<i4", []int{1}, encode(le, []int32{0})},
+		"data":    {"<c8", []int{1}, encode(le, []complex64{1 - 2i})},
+	}
+	r := z.bytes(t)
+	a, err := Read(r, int64(r.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []complex128{1 - 2i}; !reflect.DeepEqual(a.ZValues, want) {
+		t.Errorf("complex got %v, want %v", a.ZValues, want)
+	}
+
+	for name, bad := range map[string]npzFile{
+		"no format": {
+			"shape": {"<i8", []int{2}, shape},
+		},
+		"bsr": {
+			"format": {"|S3", nil, []byte("bsr")},
+			"shape":  {"<i8", []int{2}, shape},
+			"data":   {"<f8", []int{0}, nil},
+		},
+		"indptr": {
+			"format":  {"|S3", nil, []byte("csc")},
+			"shape":   {"<i8", []int{2}, shape},
+			"indptr":  {"<i4", []int{4}, encode(le, []int32{0, 2, 1, 4})},
+			"indices": {"<i4", []int{4}, encode(le, []int32{0, 1, 0, 1})},
+			"data":    {"<f8", []int{4}, encode(le, []float64{1, 3, 2, 4})},
+		},
+		"index range": {
+			"format":  {"|S3", nil, []byte("csc")},
+			"shape":   {"<i8", []int{2}, shape},
+			"indptr":  {"<i4", []int{4}, encode(le, []int32{0, 1, 2, 4})},
+			"indices": {"<i4", []int{4}, encode(le, []int32{0, 1, 0, 2})},
+			"data":    {"<f8", []int{4}, encode(le, []float64{1, 3, 2, 4})},
+		},
+		"data length": {
+			"format":  {"|S3", nil, []byte("csc")},
+			"shape":   {"<i8", []int{2}, shape},
+			"indptr":  {"<i4", []int{4}, encode(le, []int32{0, 1, 2, 4})},
+			"indices": {"<i4", []int{4}, encode(le, []int32{0, 1, 0, 1})},
+			"data":    {"<f8", []int{3}, encode(le, []float64{1, 3, 2})},
+		},
+		"scalar size": {
+			"format": {"|S4611686018427387904", nil, []byte("csc")},
+			"shape":  {"<i8", []int{2}, shape},
+		},
+		"unicode size": {
+			"format": {"<U4611686018427387904", nil, []byte("csc")},
+			"shape":  {"<i8", []int{2}, shape},
+		},
+		"object data": {
+			"format": {"|S3", nil, []byte("coo")},
+			"shape":  {"<i8", []int{2}, shape},
+			"data":   {"|O", []int{0}, nil},
+		},
+	} {
+		r := bad.bytes(t)
+		if _, err := Read(r, int64(r.Len())); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	for _, a := range []*sparse.CSC{
+		{
+			Rows: 3, Cols: 2,
+			ColPtr: []int{0, 2, 3},
+			RowInd: []int{0, 2, 1},
+			Values: []float64{1.0 / 3, -math.MaxFloat64, math.SmallestNonzeroFloat64},
+		},
+		{
+			Rows: 2, Cols: 2,
+			ColPtr:  []int{0, 0, 1},
+			RowInd:  []int{1},
+			ZValues: []complex128{1 + 2i},
+		},
+	} {
+		var buf bytes.Buffer
+		if err := Write(&buf, a); err != nil {
+			t.Fatal(err)
+		}
+		b, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("round trip got %+v, want %+v", b, a)
+		}
+
+		// The data of each array starts on a 64 byte boundary, as
+		// written by NumPy.
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var pre [10]byte
+			if _, err := io.ReadFull(rc, pre[:]); err != nil {
+				t.Fatal(err)
+			}
+			rc.Close()
+			if n := 10 + int(binary.LittleEndian.Uint16(pre[8:])); n%64 != 0 {
+				t.Errorf("%s: data offset %v", f.Name, n)
+			}
+		}
+	}
+
+	pattern := &sparse.CSC{Rows: 1, Cols: 1, ColPtr: []int{0, 1}, RowInd: []int{0}}
+	if err := Write(new(bytes.Buffer), pattern); err == nil {
+		t.Errorf("expected error for pattern matrix")
+	}
+}